@@ -14,3 +14,19 @@ complete -c gopac -s v -l version -d 'Show version information'
 
 # Help flag
 complete -c gopac -s h -l help -d 'Show help'
+
+# graph subcommand
+complete -c gopac -n __fish_use_subcommand -a graph -d 'Export a dependency graph'
+complete -c gopac -n '__fish_seen_subcommand_from graph' -s f -l format -d 'Output format' -ra 'dot mermaid'
+complete -c gopac -n '__fish_seen_subcommand_from graph' -s o -d 'Output file' -rF
+complete -c gopac -n '__fish_seen_subcommand_from graph' -l depth -d 'Maximum depth' -x
+complete -c gopac -n '__fish_seen_subcommand_from graph' -s r -l reverse -d 'Follow what requires the packages'
+complete -c gopac -n '__fish_seen_subcommand_from graph' -l optdepends -d 'Include optional dependencies'
+complete -c gopac -n '__fish_seen_subcommand_from graph' -l makedepends -d 'Include make dependencies'
+complete -c gopac -n '__fish_seen_subcommand_from graph' -a '(pacman -Qq 2>/dev/null)'
+
+# provides-cmd subcommand
+complete -c gopac -n __fish_use_subcommand -a provides-cmd -d 'List the packages that ship a command'
+complete -c gopac -n '__fish_seen_subcommand_from provides-cmd' -l tui -d 'Offer to open gopac on the candidates'
+complete -c gopac -n '__fish_seen_subcommand_from provides-cmd' -l hook -d 'Print the command-not-found hook' -ra 'bash zsh fish'
+complete -c gopac -n '__fish_seen_subcommand_from provides-cmd' -l aur -d 'Also suggest an AUR package'
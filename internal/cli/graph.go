@@ -0,0 +1,70 @@
+// Package cli implements gopac's non-interactive subcommands.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopac/internal/manager"
+)
+
+// Graph runs "gopac graph [flags] [package...]", which prints the dependency
+// graph of the given packages, or of the whole installed system when none
+// are given.
+func Graph(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	var (
+		format string
+		output string
+		opts   manager.GraphOptions
+	)
+	fs.StringVar(&format, "format", "dot", "Output format (dot, mermaid)")
+	fs.StringVar(&format, "f", "dot", "Output format (shorthand)")
+	fs.StringVar(&output, "o", "", "Write to a file instead of stdout")
+	fs.IntVar(&opts.Depth, "depth", 0, "Maximum depth from the given packages (0 for no limit)")
+	fs.BoolVar(&opts.Reverse, "reverse", false, "Follow what requires the packages instead of what they require")
+	fs.BoolVar(&opts.Reverse, "r", false, "Reverse (shorthand)")
+	fs.BoolVar(&opts.OptDepends, "optdepends", false, "Include optional dependencies")
+	fs.BoolVar(&opts.MakeDepends, "makedepends", false, "Include make dependencies (sync and AUR packages)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gopac graph [flags] [package...]")
+		fmt.Fprintln(fs.Output(), "\nExport a dependency graph as Graphviz DOT or Mermaid.")
+		fmt.Fprintln(fs.Output(), "Without packages, graphs every installed package.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nExamples:")
+		fmt.Fprintln(fs.Output(), "  gopac graph firefox | dot -Tsvg > firefox.svg")
+		fmt.Fprintln(fs.Output(), "  gopac graph -r -depth 2 -f mermaid glib2")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains(manager.GraphFormats, format) {
+		return fmt.Errorf("unknown graph format %q", format)
+	}
+
+	g, err := manager.BuildGraph(context.Background(), fs.Args(), opts)
+	if err != nil {
+		return err
+	}
+	if g.AURErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: AUR unavailable, AUR dependencies show as missing: %v\n", g.AURErr)
+	}
+
+	if output == "" {
+		return manager.WriteGraph(stdout, g, format)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := manager.WriteGraph(f, g, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
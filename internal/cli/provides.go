@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopac/internal/manager"
+)
+
+// ErrNoProviders is returned by ProvidesCmd when nothing ships the command,
+// so hooks can fall back to the shell's own message.
+var ErrNoProviders = errors.New("no package provides the command")
+
+// hooks are printed by "gopac provides-cmd -hook <shell>". Setting
+// GOPAC_CNF_AUR also asks the AUR, and GOPAC_CNF_TUI offers to open gopac on
+// the candidates.
+var hooks = map[string]string{
+	"bash": `# gopac command-not-found hook: eval "$(gopac provides-cmd -hook bash)"
+command_not_found_handle() {
+    if command -v gopac >/dev/null && gopac provides-cmd ${GOPAC_CNF_AUR:+-aur} ${GOPAC_CNF_TUI:+-tui} -- "$1"; then
+        return 127
+    fi
+    printf 'bash: %s: command not found\n' "$1" >&2
+    return 127
+}
+`,
+	"zsh": `# gopac command-not-found hook: eval "$(gopac provides-cmd -hook zsh)"
+command_not_found_handler() {
+    if (( $+commands[gopac] )) && gopac provides-cmd ${GOPAC_CNF_AUR:+-aur} ${GOPAC_CNF_TUI:+-tui} -- "$1"; then
+        return 127
+    fi
+    print -u2 "zsh: command not found: $1"
+    return 127
+}
+`,
+	"fish": `# gopac command-not-found hook: gopac provides-cmd -hook fish | source
+function fish_command_not_found
+    set -l flags
+    set -q GOPAC_CNF_AUR; and set -a flags -aur
+    set -q GOPAC_CNF_TUI; and set -a flags -tui
+    if command -q gopac; and gopac provides-cmd $flags -- $argv[1]
+        return 127
+    end
+    __fish_default_command_not_found_handler $argv
+end
+`,
+}
+
+// ProvidesCmd runs "gopac provides-cmd [flags] <name>", which lists the
+// packages that ship /usr/bin/<name>. With -tui it offers to open the TUI
+// and returns the query to start it with.
+func ProvidesCmd(args []string, stdout io.Writer) (string, error) {
+	fs := flag.NewFlagSet("provides-cmd", flag.ExitOnError)
+	var (
+		tui  bool
+		aur  bool
+		hook string
+	)
+	fs.BoolVar(&aur, "aur", false, "Suggest an AUR package named after the command when no repository has it")
+	fs.BoolVar(&tui, "tui", false, "Offer to open gopac searched on the candidates")
+	fs.StringVar(&hook, "hook", "", "Print the command-not-found hook for a shell (bash, zsh, fish)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gopac provides-cmd [flags] <command>")
+		fmt.Fprintln(fs.Output(), "\nList the packages that ship a command, from the sync files databases")
+		fmt.Fprintln(fs.Output(), "(pacman -Fy) and, with -aur, the AUR.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "\nExamples:")
+		fmt.Fprintln(fs.Output(), "  gopac provides-cmd htop")
+		fmt.Fprintln(fs.Output(), `  eval "$(gopac provides-cmd -hook bash)"`)
+		fmt.Fprintln(fs.Output(), "  gopac provides-cmd -hook fish | source")
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+
+	if hook != "" {
+		snippet, ok := hooks[hook]
+		if !ok {
+			return "", fmt.Errorf("no hook for %q (want bash, zsh or fish)", hook)
+		}
+		_, err := io.WriteString(stdout, snippet)
+		return "", err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", errors.New("expected one command name")
+	}
+
+	name := fs.Arg(0)
+	pkgs, err := manager.CommandProviders(context.Background(), name, aur)
+	if err != nil {
+		return "", err
+	}
+	if len(pkgs) == 0 {
+		return "", ErrNoProviders
+	}
+
+	fmt.Fprintf(stdout, "%s may be found in the following packages:\n", name)
+	for _, p := range pkgs {
+		label, note := p.QualifiedName(), ""
+		if p.IsAUR {
+			label = "aur/" + p.Name
+		}
+		if p.IsInstalled {
+			note = " [installed]"
+		}
+		fmt.Fprintf(stdout, "  %-30s %s%s\n", label, p.Version, note)
+	}
+
+	if !tui || !confirm(stdout, "Open in gopac? [y/N] ") {
+		return "", nil
+	}
+	if pkgs[0].IsAUR {
+		// The AUR suggestion is by name, not by file.
+		return name, nil
+	}
+	return "file:/usr/bin/" + name, nil
+}
+
+func confirm(w io.Writer, prompt string) bool {
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Fprint(w, prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(answer), "y")
+}
@@ -10,6 +10,12 @@ import (
 type Config struct {
 	AURHelper string `yaml:"aur_helper"`
 	Theme     string `yaml:"theme"`
+	NewsURL   string `yaml:"news_url"`
+	// SearchDescriptions starts gopac searching descriptions as well as
+	// names; D toggles it.
+	SearchDescriptions bool `yaml:"search_descriptions"`
+	// Ranking overrides search ranking weights by name, e.g. official: 0.
+	Ranking map[string]float64 `yaml:"ranking"`
 }
 
 func Load() (*Config, error) {
@@ -40,4 +40,16 @@ func TestLoad(t *testing.T) {
 	if cfg.AURHelper != "yay" {
 		t.Errorf("Expected AURHelper 'yay', got %q", cfg.AURHelper)
 	}
+
+	content = []byte("ranking:\n  official: 0\n  word_boundary: 7.5\n")
+	if err := os.WriteFile(configFile, content, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() returned error with ranking weights: %v", err)
+	}
+	if len(cfg.Ranking) != 2 || cfg.Ranking["official"] != 0 || cfg.Ranking["word_boundary"] != 7.5 {
+		t.Errorf("Expected two ranking weights, got %v", cfg.Ranking)
+	}
 }
@@ -3,7 +3,6 @@ package manager
 import (
 	"os"
 	"os/exec"
-	"strings"
 )
 
 var aurHelper string
@@ -36,74 +35,40 @@ func detectAURHelper() string {
 }
 
 func UpdateSystem() *exec.Cmd {
-	var cmd *exec.Cmd
-	helper := detectAURHelper()
-
-	if helper != "" && helper != "pacman" {
-		cmd = exec.Command(helper, "-Syu")
-	} else {
-		cmd = exec.Command("sudo", "pacman", "-Syu")
-	}
+	return backend.Command(Transaction{Upgrade: true, Ignore: SessionIgnored()})
+}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd
+// SelectiveUpdateCmd upgrades the system while holding back the official
+// packages in ignore, then upgrades only the given AUR packages.
+func SelectiveUpdateCmd(ignore []string, aurPkgs []string, upgradeRepos bool) *exec.Cmd {
+	return backend.Command(Transaction{
+		Upgrade:    upgradeRepos,
+		Ignore:     append(SessionIgnored(), ignore...),
+		InstallAUR: aurPkgs,
+		RepoOnly:   true,
+	})
 }
 
 func InstallOrRemove(pkgName string, isAUR bool, remove bool) *exec.Cmd {
-	var cmd *exec.Cmd
-
-	if remove {
-		cmd = exec.Command("sudo", "pacman", "-Rns", "--", pkgName)
-	} else {
-		if isAUR {
-			helper := detectAURHelper()
-			args := []string{"-S", "--", pkgName}
-			if helper == "aura" {
-				args = []string{"-A", "--", pkgName}
-			}
-			cmd = exec.Command(helper, args...)
-		} else {
-			cmd = exec.Command("sudo", "pacman", "-S", "--", pkgName)
-		}
+	switch {
+	case remove:
+		return backend.Command(Transaction{Remove: []string{pkgName}})
+	case isAUR:
+		return backend.Command(Transaction{InstallAUR: []string{pkgName}})
+	default:
+		return backend.Command(Transaction{Install: []string{pkgName}})
 	}
-
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd
 }
 
 func BulkActionCmd(toInstallOfficial []string, toInstallAUR []string, toRemove []string) *exec.Cmd {
-	var commands []string
-
-	if len(toRemove) > 0 {
-		commands = append(commands, "sudo pacman -Rns -- "+strings.Join(toRemove, " "))
-	}
-
-	if len(toInstallOfficial) > 0 {
-		commands = append(commands, "sudo pacman -S -- "+strings.Join(toInstallOfficial, " "))
-	}
-
-	if len(toInstallAUR) > 0 {
-		helper := detectAURHelper()
-		flag := "-S"
-		if helper == "aura" {
-			flag = "-A"
-		}
-		commands = append(commands, helper+" "+flag+" -- "+strings.Join(toInstallAUR, " "))
-	}
-
-	if len(commands) == 0 {
-		return nil
-	}
+	return backend.Command(Transaction{
+		Remove:     toRemove,
+		Install:    toInstallOfficial,
+		InstallAUR: toInstallAUR,
+	})
+}
 
-	// Join commands with " && " and run via sh -c
-	fullCmd := strings.Join(commands, " && ")
-	cmd := exec.Command("sh", "-c", fullCmd)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd
+// DowngradeCmd installs a package file from the cache.
+func DowngradeCmd(path string) *exec.Cmd {
+	return backend.Command(Transaction{InstallFiles: []string{path}})
 }
@@ -123,3 +123,20 @@ func TestBulkActionCmd(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectiveUpdateCmd(t *testing.T) {
+	SetAURHelper("paru")
+	defer SetAURHelper("")
+
+	cmd := SelectiveUpdateCmd([]string{"linux", "nvidia"}, []string{"yay-bin"}, true)
+	expectedArgs := []string{"sh", "-c", "sudo pacman -Syu --ignore linux,nvidia && paru -S -- yay-bin"}
+	if !slices.Equal(cmd.Args, expectedArgs) {
+		t.Errorf("Expected %v, got %v", expectedArgs, cmd.Args)
+	}
+
+	// Only AUR packages selected: no system upgrade step.
+	cmd = SelectiveUpdateCmd([]string{"linux"}, []string{"yay-bin"}, false)
+	if !slices.Equal(cmd.Args, []string{"paru", "-S", "--", "yay-bin"}) {
+		t.Errorf("Expected only the AUR upgrade, got %v", cmd.Args)
+	}
+}
@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// aurMinQuery is the shortest search term the AUR accepts; it answers
+// shorter ones with "Query arg too small".
+const aurMinQuery = 2
+
+// aurResponse is the envelope of every AUR RPC reply. Type is "search",
+// "multiinfo" or "error"; only error replies carry Error.
+type aurResponse[T any] struct {
+	Version     int    `json:"version"`
+	Type        string `json:"type"`
+	ResultCount int    `json:"resultcount"`
+	Results     []T    `json:"results"`
+	Error       string `json:"error"`
+}
+
+// aurRPC calls the AUR RPC with params and decodes the results into T.
+// Error replies become typed errors; what names the request in others.
+func aurRPC[T any](ctx context.Context, params url.Values, what string) ([]T, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", aurBaseURL+"/rpc/?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, netError(err)
+	}
+	defer resp.Body.Close()
+
+	arg := cmp.Or(params.Get("arg"), strings.Join(params["arg[]"], " "))
+	var data aurResponse[T]
+	if err := statusError(resp, what); err != nil {
+		// Rejected queries still explain themselves in an error envelope.
+		if resp.StatusCode != http.StatusTooManyRequests && json.NewDecoder(resp.Body).Decode(&data) == nil && data.Error != "" {
+			return nil, aurError(data.Error, arg)
+		}
+		return nil, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", what, err)
+	}
+	if data.Type == "error" || data.Error != "" {
+		return nil, aurError(data.Error, arg)
+	}
+	return data.Results, nil
+}
+
+// aurError types the error messages of the RPC that the UI can suggest a
+// way around.
+func aurError(msg, arg string) error {
+	switch {
+	case strings.HasPrefix(msg, "Too many package results"):
+		return fmt.Errorf("%w for %q", ErrTooManyResults, arg)
+	case strings.HasPrefix(msg, "Query arg too small"):
+		return fmt.Errorf("%w: %q", ErrQueryTooShort, arg)
+	case msg == "":
+		return fmt.Errorf("AUR: unexplained error reply for %q", arg)
+	}
+	return fmt.Errorf("AUR: %s", strings.TrimSuffix(msg, "."))
+}
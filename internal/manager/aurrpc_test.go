@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAURRPCEnvelope(t *testing.T) {
+	var status int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	origURL := aurBaseURL
+	aurBaseURL = srv.URL
+	defer func() { aurBaseURL = origURL }()
+	ctx := context.Background()
+
+	status, body = http.StatusOK, `{"version":5,"type":"search","resultcount":1,"results":[{"Name":"yay","Version":"12.3.5-1","NumVotes":2300}]}`
+	pkgs, err := searchAURContext(ctx, "yay", ByName)
+	if err != nil || len(pkgs) != 1 || pkgs[0].Votes != 2300 {
+		t.Fatalf("Expected yay, got %+v (%v)", pkgs, err)
+	}
+
+	tests := []struct {
+		status int
+		body   string
+		want   error
+		msg    string
+	}{
+		{http.StatusOK, `{"version":5,"type":"error","resultcount":0,"results":[],"error":"Too many package results."}`, ErrTooManyResults, `"li"`},
+		{http.StatusBadRequest, `{"version":5,"type":"error","resultcount":0,"results":[],"error":"Query arg too small."}`, ErrQueryTooShort, `"li"`},
+		{http.StatusOK, `{"version":5,"type":"error","resultcount":0,"results":[],"error":"Incorrect by field specified."}`, nil, "AUR: Incorrect by field specified"},
+		{http.StatusServiceUnavailable, `<html>maintenance</html>`, nil, "503"},
+		{http.StatusOK, `<html>maintenance</html>`, nil, "failed to decode AUR search"},
+	}
+	for _, tt := range tests {
+		status, body = tt.status, tt.body
+		_, err := searchAURContext(ctx, "li", ByName)
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%d %s: got %v; expected %v mentioning %s", tt.status, tt.body, err, tt.want, tt.msg)
+		}
+	}
+
+	// Info requests share the envelope.
+	status, body = http.StatusOK, `{"version":5,"type":"error","resultcount":0,"results":[],"error":"Too many package results."}`
+	if _, err := aurInfo(ctx, []string{"yay", "paru"}); !errors.Is(err, ErrTooManyResults) {
+		t.Errorf("Expected aurInfo to report the error reply, got %v", err)
+	}
+	p := Package{Name: "yay", IsAUR: true}
+	if err := getAURDetails(&p); !errors.Is(err, ErrTooManyResults) {
+		t.Errorf("Expected getAURDetails to report the error reply, got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Backend is the source of package data and the builder of the commands that
+// change the system. The default is ArchBackend; tests and non-Arch machines
+// can swap in a FakeBackend with SetBackend.
+type Backend interface {
+	Search(ctx context.Context, source SearchSource, query string, by SearchBy) ([]Package, error)
+	Details(p *Package) error
+	Installed() (map[string]string, error)
+	InstalledPackages() ([]Package, error)
+	PKGBUILD(pkgName string) (string, error)
+	Repositories() ([]string, error)
+	Updates(ctx context.Context) ([]Package, error)
+	PacmanLog() (io.ReadCloser, error)
+	CachedVersions(name string) ([]CachedPackage, error)
+	Orphans(optional bool) ([]Package, error)
+	Files(p Package) ([]string, error)
+	FileOwners(ctx context.Context, query string) ([]Package, error)
+	SyncFileOwners(ctx context.Context, query string) ([]Package, error)
+	Resolve(ctx context.Context, names []string) (map[string]Package, error)
+	Command(t Transaction) *exec.Cmd
+	CheckLock() error
+}
+
+// Transaction describes a set of changes to apply in one go.
+type Transaction struct {
+	Remove     []string
+	Install    []string
+	InstallAUR []string
+	// InstallFiles are package files installed with pacman -U, e.g. an
+	// older version from the cache.
+	InstallFiles []string
+	Upgrade      bool
+	// Ignore holds packages back during Upgrade.
+	Ignore []string
+	// RepoOnly upgrades with pacman alone so the helper doesn't touch AUR
+	// packages; the ones to upgrade go in InstallAUR instead.
+	RepoOnly bool
+}
+
+func (t Transaction) Empty() bool {
+	return len(t.Remove) == 0 && len(t.Install) == 0 && len(t.InstallAUR) == 0 && len(t.InstallFiles) == 0 && !t.Upgrade
+}
+
+var backend Backend = NewArchBackend()
+
+func SetBackend(b Backend) {
+	if b == nil {
+		b = NewArchBackend()
+	}
+	backend = b
+
+	cacheMu.Lock()
+	installedCache = nil
+	installedPkgs = nil
+	cacheMu.Unlock()
+}
+
+func CurrentBackend() Backend {
+	return backend
+}
+
+// ArchBackend reads the pacman databases directly, falling back to forking
+// pacman when they can't be read, and talks to the AUR RPC and the configured
+// AUR helper.
+type ArchBackend struct {
+	// DBPath overrides the DBPath from pacman.conf.
+	DBPath   string
+	ConfPath string
+	// LogPath overrides the LogFile from pacman.conf.
+	LogPath string
+	// TempDBPath is where update checks sync their private copy of the
+	// databases. Empty means gopac/db under os.UserCacheDir.
+	TempDBPath string
+
+	cache dbCache
+}
+
+func NewArchBackend() *ArchBackend {
+	return &ArchBackend{ConfPath: defaultConfPath}
+}
+
+func (b *ArchBackend) Command(t Transaction) *exec.Cmd {
+	var steps [][]string
+
+	if len(t.Remove) > 0 {
+		steps = append(steps, append([]string{"sudo", "pacman", "-Rns", "--"}, t.Remove...))
+	}
+
+	if t.Upgrade {
+		var step []string
+		helper := detectAURHelper()
+		if helper != "" && helper != "pacman" && !t.RepoOnly {
+			step = []string{helper, "-Syu"}
+		} else {
+			step = []string{"sudo", "pacman", "-Syu"}
+		}
+		if len(t.Ignore) > 0 {
+			step = append(step, "--ignore", strings.Join(t.Ignore, ","))
+		}
+		steps = append(steps, step)
+	}
+
+	if len(t.Install) > 0 {
+		steps = append(steps, append([]string{"sudo", "pacman", "-S", "--"}, t.Install...))
+	}
+
+	if len(t.InstallFiles) > 0 {
+		steps = append(steps, append([]string{"sudo", "pacman", "-U", "--"}, t.InstallFiles...))
+	}
+
+	if len(t.InstallAUR) > 0 {
+		helper := detectAURHelper()
+		flag := "-S"
+		if helper == "aura" {
+			flag = "-A"
+		}
+		steps = append(steps, append([]string{helper, flag, "--"}, t.InstallAUR...))
+	}
+
+	var cmd *exec.Cmd
+	switch len(steps) {
+	case 0:
+		return nil
+	case 1:
+		cmd = exec.Command(steps[0][0], steps[0][1:]...)
+	default:
+		// Join commands with " && " and run via sh -c
+		commands := make([]string, len(steps))
+		for i, s := range steps {
+			commands[i] = strings.Join(s, " ")
+		}
+		cmd = exec.Command("sh", "-c", strings.Join(commands, " && "))
+	}
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
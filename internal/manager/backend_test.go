@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchContextWithFakeBackend(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "yay-bin", IsAUR: true, Votes: 10},
+		Package{Name: "yay", IsAUR: true, Votes: 2000},
+		Package{Name: "yaycache", Version: "1.0-1", IsInstalled: true},
+		Package{Name: "vim"},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	res, err := SearchContext(context.Background(), "yay")
+	if err != nil {
+		t.Fatalf("SearchContext() returned error: %v", err)
+	}
+
+	var names []string
+	for _, p := range res {
+		names = append(names, p.Name)
+	}
+	expected := []string{"yay", "yaycache", "yay-bin"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("Expected %v, got %v", expected, names)
+	}
+	if !res[1].IsInstalled {
+		t.Errorf("Expected yaycache to be marked installed")
+	}
+}
+
+func TestFakeBackendRecordsTransactions(t *testing.T) {
+	fake := NewFakeBackend(Package{Name: "vim", IsInstalled: true})
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	if cmd := BulkActionCmd([]string{"git"}, []string{"yay"}, []string{"vim"}); cmd == nil {
+		t.Fatal("Expected non-nil command")
+	}
+
+	txs := fake.Transactions()
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txs))
+	}
+	if !slices.Equal(txs[0].Install, []string{"git"}) || !slices.Equal(txs[0].InstallAUR, []string{"yay"}) || !slices.Equal(txs[0].Remove, []string{"vim"}) {
+		t.Errorf("Unexpected transaction: %+v", txs[0])
+	}
+
+	RefreshInstalledCache()
+	installed := GetInstalledCache()
+	_, vim := installed["vim"]
+	_, git := installed["git"]
+	_, yay := installed["yay"]
+	if vim || !git || !yay {
+		t.Errorf("Unexpected installed set after transaction: %v", installed)
+	}
+}
+
+func TestUpdateSystemCommand(t *testing.T) {
+	SetAURHelper("paru")
+	defer SetAURHelper("")
+
+	cmd := UpdateSystem()
+	if !slices.Equal(cmd.Args, []string{"paru", "-Syu"}) {
+		t.Errorf("Expected 'paru -Syu', got %v", cmd.Args)
+	}
+
+	SetAURHelper("pacman")
+	cmd = UpdateSystem()
+	if !slices.Equal(cmd.Args, []string{"sudo", "pacman", "-Syu"}) {
+		t.Errorf("Expected 'sudo pacman -Syu', got %v", cmd.Args)
+	}
+}
+
+func TestSearchContextPartialResults(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "yay", IsAUR: true},
+		Package{Name: "yaycache", Repository: "extra"},
+	)
+	fake.SourceErrs = map[SearchSource]error{SourceAUR: errors.New("aur down")}
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	res, err := SearchContext(context.Background(), "yay")
+	if err != nil || len(res) != 1 || res[0].Name != "yaycache" {
+		t.Errorf("Expected the repository result despite the AUR failing, got %v (%v)", res, err)
+	}
+
+	fake.SourceErrs[SourceRepos] = errors.New("db locked")
+	if _, err := SearchContext(context.Background(), "yay"); err == nil || !strings.Contains(err.Error(), "aur down") || !strings.Contains(err.Error(), "db locked") {
+		t.Errorf("Expected both errors when every source fails, got %v", err)
+	}
+
+	pkgs, err := SearchSourceContext(context.Background(), SourceAUR, "file:/usr/bin/yay")
+	if err != nil || pkgs != nil {
+		t.Errorf("Expected no AUR file search, got %v (%v)", pkgs, err)
+	}
+
+	// The AUR refuses one-letter terms, so they aren't sent at all.
+	pkgs, err = SearchSourceContext(context.Background(), SourceAUR, "y")
+	if err != nil || pkgs != nil {
+		t.Errorf("Expected no AUR search for a one-letter term, got %v (%v)", pkgs, err)
+	}
+}
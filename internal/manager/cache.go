@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultCacheDir = "/var/cache/pacman/pkg/"
+
+// CachedPackage is a package file in one of pacman's cache directories.
+type CachedPackage struct {
+	Name         string
+	Version      string
+	Architecture string
+	Path         string
+	BuildDate    int64
+}
+
+// CachedVersions lists the versions of a package available in the cache,
+// newest first.
+func CachedVersions(name string) ([]CachedPackage, error) {
+	return backend.CachedVersions(name)
+}
+
+// cacheDirs returns the CacheDir entries from pacman.conf, or pacman's
+// default when there are none.
+func (b *ArchBackend) cacheDirs() []string {
+	if conf, err := b.pacmanConf(); err == nil && len(conf.CacheDirs) > 0 {
+		return conf.CacheDirs
+	}
+	return []string{defaultCacheDir}
+}
+
+func (b *ArchBackend) CachedVersions(name string) ([]CachedPackage, error) {
+	var pkgs []CachedPackage
+	seen := make(map[string]bool)
+	for _, dir := range b.cacheDirs() {
+		paths, err := filepath.Glob(filepath.Join(dir, name+"-*.pkg.tar*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			file := filepath.Base(path)
+			if strings.HasSuffix(file, ".sig") || seen[file] {
+				continue
+			}
+			cp, ok := parseCacheFilename(file)
+			if !ok || cp.Name != name {
+				// e.g. "foo-bar-1.0-1-any" when looking for "foo"
+				continue
+			}
+			seen[file] = true
+			cp.Path = path
+			cp.BuildDate = readBuildDate(path)
+			pkgs = append(pkgs, cp)
+		}
+	}
+
+	slices.SortStableFunc(pkgs, func(a, b CachedPackage) int {
+		return Vercmp(b.Version, a.Version)
+	})
+	return pkgs, nil
+}
+
+// parseCacheFilename splits "name-pkgver-pkgrel-arch.pkg.tar.zst".
+func parseCacheFilename(file string) (CachedPackage, bool) {
+	i := strings.Index(file, ".pkg.tar")
+	if i < 0 {
+		return CachedPackage{}, false
+	}
+	base := file[:i]
+
+	dash := strings.LastIndex(base, "-")
+	if dash <= 0 {
+		return CachedPackage{}, false
+	}
+	name, version, ok := splitNameVersion(base[:dash])
+	if !ok {
+		return CachedPackage{}, false
+	}
+	return CachedPackage{Name: name, Version: version, Architecture: base[dash+1:]}, true
+}
+
+// readBuildDate returns the builddate from a package's .PKGINFO, or 0 if it
+// can't be read. zstd and xz packages, which Go can't decompress on its own,
+// go through bsdtar; pacman depends on libarchive so it is always there.
+func readBuildDate(path string) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	r, err := openDBArchive(f)
+	if err == errUnsupportedCompression {
+		out, err := exec.Command("bsdtar", "-xOqf", path, ".PKGINFO").Output()
+		if err != nil {
+			return 0
+		}
+		return parsePkgInfoBuildDate(bytes.NewReader(out))
+	}
+	if err != nil {
+		return 0
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return 0
+		}
+		if hdr.Name == ".PKGINFO" {
+			return parsePkgInfoBuildDate(tr)
+		}
+	}
+}
+
+func parsePkgInfoBuildDate(r io.Reader) int64 {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.TrimSpace(key) == "builddate" {
+			date, _ := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			return date
+		}
+	}
+	return 0
+}
+
+var (
+	ignoreMu      sync.Mutex
+	sessionIgnore []string
+)
+
+// IgnoreForSession holds name back from every upgrade gopac runs until it
+// exits, like adding it to IgnorePkg without editing pacman.conf.
+func IgnoreForSession(name string, ignore bool) {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	i := slices.Index(sessionIgnore, name)
+	switch {
+	case ignore && i < 0:
+		sessionIgnore = append(sessionIgnore, name)
+	case !ignore && i >= 0:
+		sessionIgnore = slices.Delete(sessionIgnore, i, i+1)
+	}
+}
+
+func SessionIgnored() []string {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	return slices.Clone(sessionIgnore)
+}
+
+func IsSessionIgnored(name string) bool {
+	ignoreMu.Lock()
+	defer ignoreMu.Unlock()
+	return slices.Contains(sessionIgnore, name)
+}
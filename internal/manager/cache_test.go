@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeCachedPackage(t *testing.T, dir, file string, buildDate int64) {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	info := fmt.Sprintf("# Generated by makepkg\npkgname = x\nbuilddate = %d\nsize = 1\n", buildDate)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: ".PKGINFO", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(info))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(info)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedVersions(t *testing.T) {
+	cacheA, cacheB := t.TempDir(), t.TempDir()
+	conf := filepath.Join(t.TempDir(), "pacman.conf")
+	content := fmt.Sprintf("[options]\nCacheDir = %s\nCacheDir = %s\n\n[core]\n", cacheA, cacheB)
+	if err := os.WriteFile(conf, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeCachedPackage(t, cacheA, "bash-5.2.026-1-x86_64.pkg.tar.gz", 1700000000)
+	writeCachedPackage(t, cacheA, "bash-5.2.026-2-x86_64.pkg.tar.gz", 1710000000)
+	writeCachedPackage(t, cacheB, "bash-1:4.4-1-x86_64.pkg.tar.gz", 1600000000)
+	// Same file in both directories is only listed once.
+	writeCachedPackage(t, cacheB, "bash-5.2.026-1-x86_64.pkg.tar.gz", 1700000000)
+	// Other packages sharing the prefix and signatures are skipped.
+	writeCachedPackage(t, cacheA, "bash-completion-2.14.0-1-any.pkg.tar.gz", 1)
+	if err := os.WriteFile(filepath.Join(cacheA, "bash-5.2.026-2-x86_64.pkg.tar.gz.sig"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := &ArchBackend{ConfPath: conf}
+	pkgs, err := b.CachedVersions("bash")
+	if err != nil {
+		t.Fatalf("CachedVersions() returned error: %v", err)
+	}
+
+	var versions []string
+	for _, p := range pkgs {
+		versions = append(versions, p.Version)
+	}
+	expected := []string{"1:4.4-1", "5.2.026-2", "5.2.026-1"}
+	if !slices.Equal(versions, expected) {
+		t.Fatalf("Expected versions %v, got %v", expected, versions)
+	}
+	if pkgs[1].BuildDate != 1710000000 || pkgs[1].Architecture != "x86_64" {
+		t.Errorf("Unexpected cached package: %+v", pkgs[1])
+	}
+	if pkgs[2].Path != filepath.Join(cacheA, "bash-5.2.026-1-x86_64.pkg.tar.gz") {
+		t.Errorf("Expected the first cache dir to win, got %s", pkgs[2].Path)
+	}
+}
+
+func TestDowngradeCmdAndSessionIgnore(t *testing.T) {
+	SetAURHelper("pacman")
+	defer SetAURHelper("")
+
+	cmd := DowngradeCmd("/var/cache/pacman/pkg/bash-5.2.026-1-x86_64.pkg.tar.zst")
+	expected := []string{"sudo", "pacman", "-U", "--", "/var/cache/pacman/pkg/bash-5.2.026-1-x86_64.pkg.tar.zst"}
+	if !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Expected %v, got %v", expected, cmd.Args)
+	}
+
+	IgnoreForSession("bash", true)
+	IgnoreForSession("bash", true)
+	defer IgnoreForSession("bash", false)
+
+	cmd = UpdateSystem()
+	expected = []string{"sudo", "pacman", "-Syu", "--ignore", "bash"}
+	if !slices.Equal(cmd.Args, expected) {
+		t.Errorf("Expected %v, got %v", expected, cmd.Args)
+	}
+
+	IgnoreForSession("bash", false)
+	if IsSessionIgnored("bash") {
+		t.Error("Expected bash to no longer be ignored")
+	}
+}
@@ -0,0 +1,491 @@
+package manager
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDBPath = "/var/lib/pacman"
+
+var errUnsupportedCompression = errors.New("unsupported database compression")
+
+// dbCache keeps parsed database contents around until the file (or, for the
+// local database, the directory or a desc file) changes on disk. pacman.conf and the file
+// indexes are kept the same way.
+type dbCache struct {
+	mu         sync.Mutex
+	sync       map[string]syncDBEntry
+	local      []Package
+	localMod   time.Time
+	conf       *PacmanConf
+	confMod    time.Time
+	files      map[string]*fileIndex
+	localFiles *fileIndex
+}
+
+type syncDBEntry struct {
+	modTime time.Time
+	pkgs    []Package
+}
+
+// parseDesc reads the %FIELD% blocks used by both local desc files and the
+// entries of sync databases.
+func parseDesc(r io.Reader) map[string][]string {
+	fields := make(map[string][]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var key string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			key = ""
+			continue
+		}
+		if strings.HasPrefix(line, "%") && strings.HasSuffix(line, "%") && len(line) > 2 {
+			key = line[1 : len(line)-1]
+			if _, ok := fields[key]; !ok {
+				fields[key] = []string{}
+			}
+			continue
+		}
+		if key != "" {
+			fields[key] = append(fields[key], line)
+		}
+	}
+	return fields
+}
+
+func packageFromDesc(fields map[string][]string) Package {
+	first := func(key string) string {
+		if v := fields[key]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	unix := func(key string) int64 {
+		n, _ := strconv.ParseInt(first(key), 10, 64)
+		return n
+	}
+	size := func(key string) string {
+		if first(key) == "" {
+			return ""
+		}
+		n, _ := strconv.ParseInt(first(key), 10, 64)
+		return FormatSize(n)
+	}
+
+	p := Package{
+		Name:         first("NAME"),
+		Version:      first("VERSION"),
+		Description:  first("DESC"),
+		URL:          first("URL"),
+		Architecture: first("ARCH"),
+		Licenses:     fields["LICENSE"],
+		Groups:       fields["GROUPS"],
+		Provides:     fields["PROVIDES"],
+		Depends:      fields["DEPENDS"],
+		OptDepends:   fields["OPTDEPENDS"],
+		MakeDepends:  fields["MAKEDEPENDS"],
+		CheckDepends: fields["CHECKDEPENDS"],
+		Conflicts:    fields["CONFLICTS"],
+		Replaces:     fields["REPLACES"],
+		Packager:     first("PACKAGER"),
+		BuildDate:    unix("BUILDDATE"),
+		InstallDate:  unix("INSTALLDATE"),
+		DownloadSize: size("CSIZE"),
+		Maintainer:   "Arch Linux",
+	}
+
+	// Local entries call it SIZE, sync entries ISIZE.
+	p.InstalledSize = size("ISIZE")
+	p.InstalledBytes = unix("ISIZE")
+	if p.InstalledSize == "" {
+		p.InstalledSize = size("SIZE")
+		p.InstalledBytes = unix("SIZE")
+	}
+
+	if _, local := fields["INSTALLDATE"]; local {
+		if first("REASON") == "1" {
+			p.InstallReason = "Installed as a dependency for another package"
+			p.Dependency = true
+		} else {
+			p.InstallReason = "Explicitly installed"
+		}
+
+		var validation []string
+		for _, v := range fields["VALIDATION"] {
+			switch v {
+			case "pgp":
+				validation = append(validation, "Signature")
+			case "sha256":
+				validation = append(validation, "SHA-256 Sum")
+			case "md5":
+				validation = append(validation, "MD5 Sum")
+			case "none":
+				validation = append(validation, "None")
+			}
+		}
+		p.ValidatedBy = strings.Join(validation, "  ")
+	}
+	return p
+}
+
+// FormatSize mirrors pacman's humanize_size, switching units above 2048.
+func FormatSize(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	val := float64(bytes)
+	idx := 0
+	for ; idx < len(units)-1; idx++ {
+		if val <= 2048.0 && val >= -2048.0 {
+			break
+		}
+		val /= 1024.0
+	}
+	return fmt.Sprintf("%.2f %s", val, units[idx])
+}
+
+// parseSize reverses FormatSize for sizes printed by pacman -Qi.
+func parseSize(s string) int64 {
+	num, unit, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return 0
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	for _, u := range []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"} {
+		if u == unit {
+			return int64(val)
+		}
+		val *= 1024
+	}
+	return 0
+}
+
+// readLocalDB parses every desc file under <dbPath>/local.
+func readLocalDB(dbPath string) ([]Package, error) {
+	dir := filepath.Join(dbPath, "local")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, e.Name(), "desc"))
+		if err != nil {
+			continue
+		}
+		p := packageFromDesc(parseDesc(f))
+		f.Close()
+		if p.Name == "" {
+			continue
+		}
+		p.IsInstalled = true
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, nil
+}
+
+// readLocalVersions maps installed packages to their versions using the
+// local directory names, which are <name>-<pkgver>-<pkgrel>, without opening
+// any desc file.
+func readLocalVersions(dbPath string) (map[string]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dbPath, "local"))
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string]string)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if name, version, ok := splitNameVersion(e.Name()); ok {
+			installed[name] = version
+		}
+	}
+	return installed, nil
+}
+
+func splitNameVersion(s string) (name, version string, ok bool) {
+	rel := strings.LastIndex(s, "-")
+	if rel <= 0 {
+		return "", "", false
+	}
+	ver := strings.LastIndex(s[:rel], "-")
+	if ver <= 0 {
+		return "", "", false
+	}
+	return s[:ver], s[ver+1:], true
+}
+
+// openDBArchive returns a reader for the tar stream inside a repo database,
+// detecting the compression from the magic bytes.
+func openDBArchive(f *os.File) (io.Reader, error) {
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(6)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(br)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return bzip2.NewReader(br), nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}),
+		bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return nil, errUnsupportedCompression
+	}
+	return br, nil
+}
+
+// readSyncDB parses a <repo>.db tar archive, tagging every package with the
+// repository named by the file.
+func readSyncDB(path string) ([]Package, error) {
+	repo := strings.TrimSuffix(filepath.Base(path), ".db")
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := openDBArchive(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	var pkgs []Package
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Base(hdr.Name) != "desc" {
+			continue
+		}
+		p := packageFromDesc(parseDesc(tr))
+		if p.Name != "" {
+			p.Repository = repo
+			pkgs = append(pkgs, p)
+		}
+	}
+	return pkgs, nil
+}
+
+func (b *ArchBackend) dbPath() string {
+	if b.DBPath != "" {
+		return b.DBPath
+	}
+	if conf, err := b.pacmanConf(); err == nil && conf.DBPath != "" {
+		return conf.DBPath
+	}
+	return defaultDBPath
+}
+
+// syncPackages returns the contents of every sync database, in the order the
+// repositories appear in pacman.conf.
+func (b *ArchBackend) syncPackages() ([]Package, error) {
+	repos, err := b.Repositories()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, repo := range repos {
+		path := filepath.Join(b.dbPath(), "sync", repo+".db")
+		if _, err := os.Stat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no sync databases in %s", filepath.Join(b.dbPath(), "sync"))
+	}
+
+	b.cache.mu.Lock()
+	defer b.cache.mu.Unlock()
+	if b.cache.sync == nil {
+		b.cache.sync = make(map[string]syncDBEntry)
+	}
+
+	var all []Package
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := b.cache.sync[path]
+		if !ok || !entry.modTime.Equal(info.ModTime()) {
+			pkgs, err := readSyncDB(path)
+			if err != nil {
+				return nil, err
+			}
+			entry = syncDBEntry{modTime: info.ModTime(), pkgs: pkgs}
+			b.cache.sync[path] = entry
+		}
+		all = append(all, entry.pkgs...)
+	}
+	return all, nil
+}
+
+// localPackages returns the contents of the local database.
+func (b *ArchBackend) localPackages() ([]Package, error) {
+	// dbPath may read pacman.conf, which takes the cache lock too.
+	dbPath := b.dbPath()
+	modTime, err := localDBModTime(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b.cache.mu.Lock()
+	defer b.cache.mu.Unlock()
+	if b.cache.local != nil && b.cache.localMod.Equal(modTime) {
+		return b.cache.local, nil
+	}
+
+	pkgs, err := readLocalDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	b.cache.local = pkgs
+	b.cache.localMod = modTime
+	return pkgs, nil
+}
+
+// localDBModTime is when the local database last changed. The directory
+// changes when packages come and go, but pacman -D --asdeps/--asexplicit
+// rewrites desc files in place, so their times count too.
+func localDBModTime(dbPath string) (time.Time, error) {
+	dir := filepath.Join(dbPath, "local")
+	info, err := os.Stat(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := info.ModTime()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(dir, e.Name(), "desc")); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// searchDB matches the query against a field of the packages in the sync
+// databases.
+func (b *ArchBackend) searchDB(query string, by SearchBy) ([]Package, error) {
+	all, err := b.syncPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, p := range all {
+		if matchesSearch(p, by, query) {
+			pkgs = append(pkgs, Package{
+				Name:        p.Name,
+				Version:     p.Version,
+				Description: p.Description,
+				Repository:  p.Repository,
+				Maintainer:  p.Maintainer,
+				// For the query filters.
+				Licenses:       p.Licenses,
+				BuildDate:      p.BuildDate,
+				InstalledBytes: p.InstalledBytes,
+			})
+		}
+	}
+	return pkgs, nil
+}
+
+// detailsFromDB fills p from the local database when installed, otherwise
+// from the sync databases, computing Required By like pacman does.
+func (b *ArchBackend) detailsFromDB(p *Package) error {
+	var (
+		pool []Package
+		err  error
+	)
+	if p.IsInstalled {
+		pool, err = b.localPackages()
+	} else {
+		pool, err = b.syncPackages()
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range pool {
+		if candidate.Name != p.Name {
+			continue
+		}
+		if !p.IsInstalled && p.Repository != "" && candidate.Repository != p.Repository {
+			continue
+		}
+		installed, repo := p.IsInstalled, p.Repository
+		*p = candidate
+		p.IsInstalled = installed
+		if p.Repository == "" {
+			p.Repository = repo
+		}
+		p.RequiredBy = requiredBy(candidate, pool)
+		p.Detailed = true
+		return nil
+	}
+	return fmt.Errorf("package %s not found in database", p.Name)
+}
+
+// requiredBy lists the packages in pool that depend on target by name or on
+// anything target provides.
+func requiredBy(target Package, pool []Package) []string {
+	satisfies := map[string]bool{target.Name: true}
+	for _, prov := range target.Provides {
+		satisfies[depName(prov)] = true
+	}
+
+	var names []string
+	for _, p := range pool {
+		for _, dep := range p.Depends {
+			if satisfies[depName(dep)] {
+				names = append(names, p.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// depName strips the version constraint from a dependency string such as
+// "glibc>=2.38" or "sh=5.2".
+func depName(dep string) string {
+	if i := strings.IndexAny(dep, "<>="); i >= 0 {
+		return dep[:i]
+	}
+	return dep
+}
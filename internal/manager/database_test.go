@@ -0,0 +1,250 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeLocalEntry creates <dbPath>/local/<name>-<version>/desc.
+func writeLocalEntry(t *testing.T, dbPath, name, version, desc string) {
+	t.Helper()
+	dir := filepath.Join(dbPath, "local", name+"-"+version)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "desc"), []byte(desc), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeSyncDB creates a gzip-compressed <dbPath>/sync/<repo>.db whose keys
+// are directory names and values desc contents.
+func writeSyncDB(t *testing.T, dbPath, repo string, entries map[string]string) {
+	t.Helper()
+	writeRepoArchive(t, filepath.Join(dbPath, "sync", repo+".db"), "desc", entries)
+}
+
+// writeRepoArchive creates a gzip-compressed tar with one <key>/<member>
+// file per entry, like the .db and .files sync databases.
+func writeRepoArchive(t *testing.T, path, member string, entries map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/" + member, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestDB(t *testing.T) string {
+	t.Helper()
+	dbPath := t.TempDir()
+
+	writeLocalEntry(t, dbPath, "glibc", "2.40-1", `%NAME%
+glibc
+
+%VERSION%
+2.40-1
+
+%DESC%
+GNU C Library
+
+%INSTALLDATE%
+1700000000
+
+%REASON%
+1
+
+%SIZE%
+50331648
+
+%VALIDATION%
+pgp
+
+%PROVIDES%
+libc.so=6-64
+`)
+	writeLocalEntry(t, dbPath, "bash", "5.2.026-2", `%NAME%
+bash
+
+%VERSION%
+5.2.026-2
+
+%DESC%
+The GNU Bourne Again shell
+
+%INSTALLDATE%
+1700000001
+
+%DEPENDS%
+readline
+libc.so=6-64
+`)
+	writeLocalEntry(t, dbPath, "yay", "12.3.5-1", `%NAME%
+yay
+
+%VERSION%
+12.3.5-1
+
+%INSTALLDATE%
+1700000002
+
+%DEPENDS%
+glibc>=2.38
+`)
+
+	writeSyncDB(t, dbPath, "core", map[string]string{
+		"glibc-2.40-1":   "%NAME%\nglibc\n\n%VERSION%\n2.40-1\n\n%DESC%\nGNU C Library\n\n%CSIZE%\n10485760\n\n%ISIZE%\n50331648\n\n%BUILDDATE%\n1699999999\n",
+		"bash-5.2.026-2": "%NAME%\nbash\n\n%VERSION%\n5.2.026-2\n\n%DESC%\nThe GNU Bourne Again shell\n\n%DEPENDS%\nglibc\nreadline\n",
+	})
+	writeSyncDB(t, dbPath, "extra", map[string]string{
+		"glib2-2.80.0-1": "%NAME%\nglib2\n\n%VERSION%\n2.80.0-1\n\n%DESC%\nLow level core library\n",
+	})
+	return dbPath
+}
+
+func TestPackageFromDesc(t *testing.T) {
+	dbPath := newTestDB(t)
+	pkgs, err := readLocalDB(dbPath)
+	if err != nil {
+		t.Fatalf("readLocalDB() returned error: %v", err)
+	}
+	if len(pkgs) != 3 {
+		t.Fatalf("Expected 3 local packages, got %d", len(pkgs))
+	}
+
+	var glibc Package
+	for _, p := range pkgs {
+		if p.Name == "glibc" {
+			glibc = p
+		}
+	}
+	if glibc.Version != "2.40-1" || glibc.Description != "GNU C Library" {
+		t.Errorf("Unexpected glibc entry: %+v", glibc)
+	}
+	if glibc.InstalledSize != "48.00 MiB" {
+		t.Errorf("Expected installed size '48.00 MiB', got %q", glibc.InstalledSize)
+	}
+	if glibc.InstalledBytes != 50331648 {
+		t.Errorf("Expected 50331648 installed bytes, got %d", glibc.InstalledBytes)
+	}
+	if glibc.InstallReason != "Installed as a dependency for another package" || !glibc.Dependency {
+		t.Errorf("Unexpected install reason %q", glibc.InstallReason)
+	}
+	if glibc.ValidatedBy != "Signature" {
+		t.Errorf("Expected validation 'Signature', got %q", glibc.ValidatedBy)
+	}
+	if glibc.InstallDate != 1700000000 {
+		t.Errorf("Expected install date 1700000000, got %d", glibc.InstallDate)
+	}
+}
+
+func TestArchBackendFromDatabase(t *testing.T) {
+	dbPath := newTestDB(t)
+	b := &ArchBackend{DBPath: dbPath, ConfPath: filepath.Join(dbPath, "missing.conf")}
+
+	installed, err := b.Installed()
+	if err != nil {
+		t.Fatalf("Installed() returned error: %v", err)
+	}
+	if installed["glibc"] != "2.40-1" || installed["bash"] != "5.2.026-2" || installed["yay"] != "12.3.5-1" || len(installed) != 3 {
+		t.Errorf("Unexpected installed set: %v", installed)
+	}
+
+	res, err := b.searchDB("GLIB", ByName)
+	if err != nil {
+		t.Fatalf("searchDB() returned error: %v", err)
+	}
+	var names []string
+	for _, p := range res {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"glib2", "glibc"}) {
+		t.Errorf("Expected [glib2 glibc], got %v", names)
+	}
+
+	local := Package{Name: "glibc", IsInstalled: true}
+	if err := b.Details(&local); err != nil {
+		t.Fatalf("Details() returned error: %v", err)
+	}
+	if !slices.Equal(local.RequiredBy, []string{"bash", "yay"}) {
+		t.Errorf("Expected glibc to be required by [bash yay], got %v", local.RequiredBy)
+	}
+
+	sync := Package{Name: "glibc"}
+	if err := b.Details(&sync); err != nil {
+		t.Fatalf("Details() returned error: %v", err)
+	}
+	if sync.DownloadSize != "10.00 MiB" || sync.BuildDate != 1699999999 {
+		t.Errorf("Unexpected sync details: %+v", sync)
+	}
+	if !slices.Equal(sync.RequiredBy, []string{"bash"}) {
+		t.Errorf("Expected sync glibc to be required by [bash], got %v", sync.RequiredBy)
+	}
+}
+
+func TestLocalPackagesCache(t *testing.T) {
+	dbPath := t.TempDir()
+	desc := "%NAME%\nbash\n\n%VERSION%\n5.2.026-2\n\n%INSTALLDATE%\n1\n\n%REASON%\n1\n"
+	writeLocalEntry(t, dbPath, "bash", "5.2.026-2", desc)
+	b := &ArchBackend{DBPath: dbPath}
+
+	pkgs, err := b.localPackages()
+	if err != nil || len(pkgs) != 1 || !pkgs[0].Dependency {
+		t.Fatalf("Expected bash as a dependency, got %+v (%v)", pkgs, err)
+	}
+
+	// pacman -D --asexplicit rewrites desc without touching the directory.
+	dir := filepath.Join(dbPath, "local")
+	info, _ := os.Stat(dir)
+	writeLocalEntry(t, dbPath, "bash", "5.2.026-2", "%NAME%\nbash\n\n%VERSION%\n5.2.026-2\n\n%INSTALLDATE%\n1\n")
+	later := info.ModTime().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "bash-5.2.026-2", "desc"), later, later)
+	os.Chtimes(dir, info.ModTime(), info.ModTime())
+	if pkgs, err := b.localPackages(); err != nil || pkgs[0].Dependency {
+		t.Errorf("Expected the new install reason, got %+v (%v)", pkgs, err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		0:          "0.00 B",
+		2048:       "2048.00 B",
+		4096:       "4.00 KiB",
+		5767168:    "5.50 MiB",
+		3221225472: "3.00 GiB",
+	}
+	for in, expected := range tests {
+		if got := FormatSize(in); got != expected {
+			t.Errorf("FormatSize(%d) = %q, expected %q", in, got, expected)
+		}
+	}
+}
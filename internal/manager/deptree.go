@@ -0,0 +1,244 @@
+package manager
+
+import (
+	"context"
+	"slices"
+)
+
+// DepStatus says where a dependency would come from.
+type DepStatus int
+
+const (
+	DepInstalled DepStatus = iota
+	DepRepo                // available in a sync repository, not installed
+	DepAUR                 // only available from the AUR
+	DepMissing             // nothing satisfies it
+)
+
+func (s DepStatus) String() string {
+	switch s {
+	case DepInstalled:
+		return "installed"
+	case DepRepo:
+		return "repo"
+	case DepAUR:
+		return "AUR"
+	default:
+		return "missing"
+	}
+}
+
+// maxDepTreeDepth guards against runaway trees; real ones are far shallower.
+const maxDepTreeDepth = 32
+
+// DepNode is one dependency in a tree. Packages are expanded once: later
+// occurrences are marked Seen and cycles back to an ancestor Cycle, and
+// neither has Children.
+type DepNode struct {
+	// Dep is the dependency as written, e.g. "sh" or "glibc>=2.38".
+	Dep     string
+	Package Package
+	Status  DepStatus
+	// Provider is set when Dep is satisfied through another package's
+	// provides, e.g. bash for sh.
+	Provider bool
+	Seen     bool
+	Cycle    bool
+	Children []*DepNode
+	// AURErr is set on the root when the AUR couldn't be asked; what only
+	// the AUR has is then marked missing.
+	AURErr error
+}
+
+// Name is the name of the package that satisfies the dependency, or the
+// dependency itself when nothing does.
+func (n *DepNode) Name() string {
+	if n.Package.Name != "" {
+		return n.Package.Name
+	}
+	return depName(n.Dep)
+}
+
+// DepTreeStats summarises the transitive closure of a tree, counting every
+// package once.
+type DepTreeStats struct {
+	Packages     int
+	ToInstall    int
+	InstallBytes int64 // installed size of what isn't installed yet
+	TotalBytes   int64
+	Missing      []string
+}
+
+// DependencyTree resolves p's dependencies recursively through the installed
+// packages, the sync repositories (including provides) and the AUR.
+func DependencyTree(ctx context.Context, p Package) (*DepNode, error) {
+	if !p.Detailed {
+		if err := backend.Details(&p); err != nil {
+			return nil, err
+		}
+	}
+	root := &DepNode{Dep: p.Name, Package: p, Status: statusOf(p)}
+	resolved := map[string]Package{}
+	expanded := map[string]bool{p.Name: true}
+
+	// Resolve one level at a time so the AUR is asked once per level.
+	level := []*DepNode{root}
+	ancestors := map[*DepNode][]string{root: {p.Name}}
+	for depth := 0; len(level) > 0 && depth < maxDepTreeDepth; depth++ {
+		var wanted []string
+		for _, n := range level {
+			for _, dep := range n.Package.Depends {
+				if _, ok := resolved[depName(dep)]; !ok && !slices.Contains(wanted, depName(dep)) {
+					wanted = append(wanted, depName(dep))
+				}
+			}
+		}
+		if len(wanted) > 0 {
+			found, err := backend.Resolve(ctx, wanted)
+			switch {
+			case err == nil:
+			case found == nil || ctx.Err() != nil:
+				return nil, err
+			default:
+				root.AURErr = err
+			}
+			for _, name := range wanted {
+				resolved[name] = found[name]
+			}
+		}
+
+		var next []*DepNode
+		for _, n := range level {
+			for _, dep := range n.Package.Depends {
+				child := &DepNode{Dep: dep, Package: resolved[depName(dep)], Status: DepMissing}
+				if child.Package.Name != "" {
+					child.Status = statusOf(child.Package)
+					child.Provider = child.Package.Name != depName(dep)
+				}
+				n.Children = append(n.Children, child)
+
+				name := child.Name()
+				switch {
+				case slices.Contains(ancestors[n], name):
+					child.Cycle = true
+				case child.Status == DepMissing:
+					// Nothing to expand.
+				case expanded[name]:
+					child.Seen = true
+				default:
+					expanded[name] = true
+					ancestors[child] = append(slices.Clone(ancestors[n]), name)
+					next = append(next, child)
+				}
+			}
+		}
+		level = next
+	}
+	return root, nil
+}
+
+func statusOf(p Package) DepStatus {
+	switch {
+	case p.IsInstalled:
+		return DepInstalled
+	case p.IsAUR:
+		return DepAUR
+	default:
+		return DepRepo
+	}
+}
+
+// Stats walks the tree below n.
+func (n *DepNode) Stats() DepTreeStats {
+	var stats DepTreeStats
+	seen := map[string]bool{n.Name(): true}
+	var walk func(*DepNode)
+	walk = func(d *DepNode) {
+		for _, c := range d.Children {
+			name := c.Name()
+			if !seen[name] {
+				seen[name] = true
+				if c.Status == DepMissing {
+					stats.Missing = append(stats.Missing, c.Dep)
+				} else {
+					stats.Packages++
+					stats.TotalBytes += c.Package.InstalledBytes
+					if c.Status != DepInstalled {
+						stats.ToInstall++
+						stats.InstallBytes += c.Package.InstalledBytes
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return stats
+}
+
+// Resolve finds the packages that satisfy the given dependency names:
+// installed packages first, then the sync repositories by name and by
+// provides, then the AUR by name. Unsatisfied names are left out. When the
+// AUR can't be asked, what was satisfied without it comes with the error.
+func (b *ArchBackend) Resolve(ctx context.Context, names []string) (map[string]Package, error) {
+	local, err := b.localPackages()
+	if err != nil {
+		return nil, err
+	}
+	remote, err := b.syncPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	found := resolveFrom(names, local, remote)
+
+	var unresolved []string
+	for _, name := range names {
+		if _, ok := found[name]; !ok {
+			unresolved = append(unresolved, name)
+		}
+	}
+	if len(unresolved) > 0 {
+		aurPkgs, err := aurInfo(ctx, unresolved)
+		if err != nil {
+			return found, err
+		}
+		for _, name := range unresolved {
+			if p, ok := aurPkgs[name]; ok {
+				found[name] = p
+			}
+		}
+	}
+	return found, nil
+}
+
+// resolveFrom satisfies names from the installed packages, preferring them,
+// then from the sync packages, by name before provides.
+func resolveFrom(names []string, local, remote []Package) map[string]Package {
+	found := make(map[string]Package)
+	for _, pool := range [][]Package{local, remote} {
+		byName := make(map[string]Package, len(pool))
+		byProvides := make(map[string]Package)
+		for _, p := range pool {
+			if _, ok := byName[p.Name]; !ok {
+				byName[p.Name] = p
+			}
+			for _, prov := range p.Provides {
+				if _, ok := byProvides[depName(prov)]; !ok {
+					byProvides[depName(prov)] = p
+				}
+			}
+		}
+		for _, name := range names {
+			if _, ok := found[name]; ok {
+				continue
+			}
+			if p, ok := byName[name]; ok {
+				found[name] = p
+			} else if p, ok := byProvides[name]; ok {
+				found[name] = p
+			}
+		}
+	}
+	return found
+}
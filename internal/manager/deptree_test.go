@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDependencyTree(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "app", Repository: "extra", Depends: []string{"libfoo>=1.0", "sh", "helper", "ghost"}},
+		Package{Name: "libfoo", Repository: "extra", Depends: []string{"glibc", "libbar"}, InstalledBytes: 100},
+		Package{Name: "libbar", Repository: "extra", Depends: []string{"libfoo"}, InstalledBytes: 50},
+		Package{Name: "glibc", Repository: "core", IsInstalled: true, InstalledBytes: 1000},
+		Package{Name: "bash", Repository: "core", IsInstalled: true, Provides: []string{"sh=5.2"}, Depends: []string{"glibc"}, InstalledBytes: 10},
+		Package{Name: "helper", IsAUR: true, Depends: []string{"glibc"}, InstalledBytes: 5},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	root, err := DependencyTree(context.Background(), Package{Name: "app", Repository: "extra"})
+	if err != nil {
+		t.Fatalf("DependencyTree() returned error: %v", err)
+	}
+
+	var names []string
+	for _, c := range root.Children {
+		names = append(names, c.Name())
+	}
+	if !slices.Equal(names, []string{"libfoo", "bash", "helper", "ghost"}) {
+		t.Fatalf("Unexpected direct dependencies %v", names)
+	}
+
+	libfoo, sh, helper, ghost := root.Children[0], root.Children[1], root.Children[2], root.Children[3]
+	if libfoo.Status != DepRepo || libfoo.Dep != "libfoo>=1.0" {
+		t.Errorf("Unexpected libfoo node: %+v", libfoo)
+	}
+	if sh.Status != DepInstalled || !sh.Provider {
+		t.Errorf("Expected sh to be provided by installed bash, got %+v", sh)
+	}
+	if helper.Status != DepAUR {
+		t.Errorf("Expected helper to come from the AUR, got %v", helper.Status)
+	}
+	if ghost.Status != DepMissing || ghost.Children != nil {
+		t.Errorf("Expected ghost to be missing, got %+v", ghost)
+	}
+
+	// libfoo -> libbar -> libfoo is a cycle; glibc under bash was already
+	// expanded under libfoo.
+	libbar := libfoo.Children[1]
+	if len(libbar.Children) != 1 || !libbar.Children[0].Cycle {
+		t.Errorf("Expected libbar's libfoo to be marked as a cycle, got %+v", libbar.Children)
+	}
+	if glibc := sh.Children[0]; !glibc.Seen || glibc.Children != nil {
+		t.Errorf("Expected glibc under bash to be marked as seen, got %+v", glibc)
+	}
+
+	stats := root.Stats()
+	if stats.Packages != 5 || stats.ToInstall != 3 || stats.InstallBytes != 155 || stats.TotalBytes != 1165 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if !slices.Equal(stats.Missing, []string{"ghost"}) {
+		t.Errorf("Expected ghost to be missing, got %v", stats.Missing)
+	}
+	if root.AURErr != nil {
+		t.Errorf("Expected no AUR error, got %v", root.AURErr)
+	}
+
+	// Offline, the repository part of the tree still resolves.
+	fake.SourceErrs = map[SearchSource]error{SourceAUR: ErrOffline}
+	root, err = DependencyTree(context.Background(), Package{Name: "app", Repository: "extra"})
+	if err != nil {
+		t.Fatalf("Expected a tree without the AUR, got %v", err)
+	}
+	if !errors.Is(root.AURErr, ErrOffline) || root.Children[0].Status != DepRepo || root.Children[2].Status != DepMissing {
+		t.Errorf("Expected libfoo resolved and helper missing with a note, got %+v", root)
+	}
+}
@@ -0,0 +1,61 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Errors the UI tells apart so it can say what went wrong and offer a way
+// out. Match them with errors.Is; the wrapped message has the details.
+var (
+	ErrOffline        = errors.New("network unreachable")
+	ErrRateLimited    = errors.New("AUR rate limit reached")
+	ErrTooManyResults = errors.New("too many package results")
+	ErrQueryTooShort  = errors.New("AUR query too short")
+	ErrDBLocked       = errors.New("pacman database is locked")
+)
+
+// netError marks failures to reach a server at all as ErrOffline.
+func netError(err error) error {
+	var (
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+		urlErr *url.Error
+	)
+	if errors.As(err, &dnsErr) || errors.As(err, &opErr) || (errors.As(err, &urlErr) && urlErr.Timeout()) {
+		return fmt.Errorf("%w: %v", ErrOffline, err)
+	}
+	return err
+}
+
+// statusError turns an unsuccessful HTTP response into an error, rate
+// limiting into ErrRateLimited.
+func statusError(resp *http.Response, what string) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", ErrRateLimited, resp.Status)
+	default:
+		return fmt.Errorf("failed to fetch %s: %s", what, resp.Status)
+	}
+}
+
+// CheckLock returns ErrDBLocked while another pacman holds the database, so
+// a transaction isn't started only to fail.
+func CheckLock() error {
+	return backend.CheckLock()
+}
+
+func (b *ArchBackend) CheckLock() error {
+	lock := filepath.Join(b.dbPath(), "db.lck")
+	if _, err := os.Stat(lock); err == nil {
+		return fmt.Errorf("%w: %s exists; remove it if no pacman is running", ErrDBLocked, lock)
+	}
+	return nil
+}
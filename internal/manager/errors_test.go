@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNetError(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "aur.archlinux.org"}
+	if err := netError(dnsErr); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected a DNS failure to be ErrOffline, got %v", err)
+	}
+	if err := netError(errors.New("boom")); errors.Is(err, ErrOffline) {
+		t.Errorf("Expected other errors to pass through, got %v", err)
+	}
+}
+
+func TestAURErrors(t *testing.T) {
+	var status int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	origURL := aurBaseURL
+	aurBaseURL = srv.URL
+	defer func() { aurBaseURL = origURL }()
+
+	b := &ArchBackend{}
+	status, body = http.StatusTooManyRequests, "slow down"
+	if _, err := b.Search(context.Background(), SourceAUR, "vim", ByName); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	status, body = http.StatusOK, `{"version":5,"type":"error","resultcount":0,"results":[],"error":"Too many package results."}`
+	if _, err := b.Search(context.Background(), SourceAUR, "a", ByName); !errors.Is(err, ErrTooManyResults) {
+		t.Errorf("Expected ErrTooManyResults, got %v", err)
+	}
+}
+
+func TestCheckLock(t *testing.T) {
+	dbPath := t.TempDir()
+	b := &ArchBackend{DBPath: dbPath}
+	if err := b.CheckLock(); err != nil {
+		t.Fatalf("Expected no lock, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dbPath, "db.lck"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.CheckLock(); !errors.Is(err, ErrDBLocked) {
+		t.Errorf("Expected ErrDBLocked, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeBackend is an in-memory Backend for tests and for running the TUI on
+// machines without pacman. Transactions are recorded instead of executed.
+type FakeBackend struct {
+	Packages       []Package
+	InstalledPkgs  map[string]bool
+	PKGBUILDs      map[string]string
+	Repos          []string
+	PendingUpdates []Package
+	Log            string
+	Cache          map[string][]CachedPackage
+	FileLists      map[string][]string
+	SearchErr      error
+	// SourceErrs fails the searches of single sources; SourceAUR also fails
+	// the AUR step of Resolve.
+	SourceErrs map[SearchSource]error
+	// DBLocked makes CheckLock report another pacman running.
+	DBLocked bool
+	// DetailsErr fails every Details call.
+	DetailsErr error
+	// UpdatesErr comes with PendingUpdates, e.g. an *UpdatesWarning.
+	UpdatesErr error
+
+	mu           sync.Mutex
+	transactions []Transaction
+}
+
+func NewFakeBackend(pkgs ...Package) *FakeBackend {
+	f := &FakeBackend{
+		Packages:      pkgs,
+		InstalledPkgs: make(map[string]bool),
+		PKGBUILDs:     make(map[string]string),
+	}
+	for _, p := range pkgs {
+		if p.IsInstalled {
+			f.InstalledPkgs[p.Name] = true
+		}
+	}
+	return f
+}
+
+func (f *FakeBackend) Search(ctx context.Context, source SearchSource, query string, by SearchBy) ([]Package, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.SourceErrs[source]; err != nil {
+		return nil, err
+	}
+	if f.SearchErr != nil {
+		return nil, f.SearchErr
+	}
+
+	var results []Package
+	for _, p := range f.Packages {
+		if p.IsAUR == (source == SourceAUR) && matchesSearch(p, by, query) {
+			p.Detailed = false
+			results = append(results, p)
+		}
+	}
+	return results, nil
+}
+
+func (f *FakeBackend) Details(p *Package) error {
+	if f.DetailsErr != nil {
+		return f.DetailsErr
+	}
+	for _, fp := range f.Packages {
+		if fp.Name == p.Name && fp.IsAUR == p.IsAUR && (p.Repository == "" || fp.Repository == p.Repository) {
+			installed := p.IsInstalled
+			*p = fp
+			p.IsInstalled = installed
+			p.Detailed = true
+			return nil
+		}
+	}
+	return fmt.Errorf("no info found for %s", p.Name)
+}
+
+// Installed maps the installed set to versions, taking each version from the
+// first of the fake's packages with that name.
+func (f *FakeBackend) Installed() (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	installed := make(map[string]string, len(f.InstalledPkgs))
+	for name, ok := range f.InstalledPkgs {
+		if !ok {
+			continue
+		}
+		installed[name] = ""
+		for _, p := range f.Packages {
+			if p.Name == name {
+				installed[name] = p.Version
+				break
+			}
+		}
+	}
+	return installed, nil
+}
+
+// InstalledPackages returns the fake's installed packages. AUR ones come
+// back foreign, as they would from the local database.
+func (f *FakeBackend) InstalledPackages() ([]Package, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pkgs []Package
+	seen := make(map[string]bool)
+	for _, p := range f.Packages {
+		if !f.InstalledPkgs[p.Name] || seen[p.Name] {
+			continue
+		}
+		seen[p.Name] = true
+		p.IsInstalled = true
+		if p.InstalledVersion == "" {
+			p.InstalledVersion = p.Version
+		}
+		if p.IsAUR {
+			p.IsAUR = false
+			p.Foreign = true
+			p.Repository = "local"
+		}
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs, nil
+}
+
+func (f *FakeBackend) PKGBUILD(pkgName string) (string, error) {
+	build, ok := f.PKGBUILDs[pkgName]
+	if !ok {
+		return "", fmt.Errorf("failed to fetch PKGBUILD: 404 Not Found")
+	}
+	return build, nil
+}
+
+// Repositories returns Repos, or the repositories of the fake's packages in
+// the order they first appear.
+func (f *FakeBackend) Repositories() ([]string, error) {
+	if f.Repos != nil {
+		return f.Repos, nil
+	}
+	var repos []string
+	for _, p := range f.Packages {
+		if !p.IsAUR && p.Repository != "" && !slices.Contains(repos, p.Repository) {
+			repos = append(repos, p.Repository)
+		}
+	}
+	return repos, nil
+}
+
+func (f *FakeBackend) Updates(ctx context.Context) ([]Package, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.PendingUpdates, f.UpdatesErr
+}
+
+func (f *FakeBackend) PacmanLog() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(f.Log)), nil
+}
+
+func (f *FakeBackend) CachedVersions(name string) ([]CachedPackage, error) {
+	return f.Cache[name], nil
+}
+
+func (f *FakeBackend) Files(p Package) ([]string, error) {
+	files, ok := f.FileLists[p.Name]
+	if !ok {
+		return nil, fmt.Errorf("no file list for %s", p.Name)
+	}
+	return files, nil
+}
+
+// FileOwners matches the query against FileLists, installed packages first.
+func (f *FakeBackend) FileOwners(ctx context.Context, query string) ([]Package, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	match := fileMatcher(query)
+	var installed, available []Package
+	for _, p := range f.Packages {
+		if !slices.ContainsFunc(f.FileLists[p.Name], match) {
+			continue
+		}
+		if f.InstalledPkgs[p.Name] {
+			p.IsInstalled = true
+			installed = append(installed, p)
+		} else {
+			available = append(available, p)
+		}
+	}
+	return append(installed, available...), nil
+}
+
+// SyncFileOwners matches the file lists of the fake's repository packages.
+func (f *FakeBackend) SyncFileOwners(ctx context.Context, query string) ([]Package, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	match := fileMatcher(query)
+	var owners []Package
+	for _, p := range f.Packages {
+		if !p.IsAUR && slices.ContainsFunc(f.FileLists[p.Name], match) {
+			owners = append(owners, p)
+		}
+	}
+	return owners, nil
+}
+
+// Orphans applies the -Qdt rules to the fake's installed packages.
+func (f *FakeBackend) Orphans(optional bool) ([]Package, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var local []Package
+	for _, p := range f.Packages {
+		if f.InstalledPkgs[p.Name] && !p.IsAUR {
+			local = append(local, p)
+		}
+	}
+	return findOrphans(local, optional), nil
+}
+
+// Resolve satisfies names from the installed packages, then the repository
+// packages by name and provides, then the AUR packages by name.
+func (f *FakeBackend) Resolve(ctx context.Context, names []string) (map[string]Package, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var local, remote []Package
+	aur := make(map[string]Package)
+	for _, p := range f.Packages {
+		switch {
+		case f.InstalledPkgs[p.Name]:
+			p.IsInstalled = true
+			local = append(local, p)
+		case p.IsAUR:
+			aur[p.Name] = p
+		default:
+			remote = append(remote, p)
+		}
+	}
+	found := resolveFrom(names, local, remote)
+	for _, name := range names {
+		if _, ok := found[name]; !ok {
+			if err := f.SourceErrs[SourceAUR]; err != nil {
+				return found, err
+			}
+			if p, ok := aur[name]; ok {
+				found[name] = p
+			}
+		}
+	}
+	return found, nil
+}
+
+// Command records the transaction and applies it to the fake's installed set.
+// The returned command is a no-op so it can be run through tea.ExecProcess.
+func (f *FakeBackend) Command(t Transaction) *exec.Cmd {
+	if t.Empty() {
+		return nil
+	}
+
+	f.mu.Lock()
+	f.transactions = append(f.transactions, t)
+	for _, name := range t.Remove {
+		delete(f.InstalledPkgs, name)
+	}
+	for _, name := range slices.Concat(t.Install, t.InstallAUR) {
+		// Drop the repo/ qualifier.
+		f.InstalledPkgs[name[strings.LastIndex(name, "/")+1:]] = true
+	}
+	for _, path := range t.InstallFiles {
+		if cp, ok := parseCacheFilename(filepath.Base(path)); ok {
+			f.InstalledPkgs[cp.Name] = true
+		}
+	}
+	f.mu.Unlock()
+
+	return exec.Command("true")
+}
+
+func (f *FakeBackend) Transactions() []Transaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Transaction(nil), f.transactions...)
+}
+
+func (f *FakeBackend) CheckLock() error {
+	if f.DBLocked {
+		return fmt.Errorf("%w: db.lck exists", ErrDBLocked)
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package manager
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Files lists the paths p installs, with directories ending in "/". Installed
+// packages are read from the local database and the others from the sync
+// files databases (pacman -Fy).
+func Files(p Package) ([]string, error) {
+	return backend.Files(p)
+}
+
+func (b *ArchBackend) Files(p Package) ([]string, error) {
+	if p.IsInstalled {
+		files, err := b.localFiles(p.Name)
+		if err != nil {
+			return filesFromPacman("-Qlq", p.Name)
+		}
+		return files, nil
+	}
+	if p.IsAUR {
+		return nil, errors.New("file lists aren't available for AUR packages")
+	}
+
+	if p.Repository != "" {
+		path := filepath.Join(b.dbPath(), "sync", p.Repository+".files")
+		var files []string
+		found := false
+		err := scanFilesDB(path, func(name, _ string, list []string) bool {
+			if name == p.Name {
+				files, found = list, true
+				return false
+			}
+			return true
+		})
+		if err == nil && found {
+			return files, nil
+		}
+	}
+	return filesFromPacman("-Flq", p.QualifiedName())
+}
+
+// localFiles reads the %FILES% block of an installed package.
+func (b *ArchBackend) localFiles(name string) ([]string, error) {
+	versions, err := readLocalVersions(b.dbPath())
+	if err != nil {
+		return nil, err
+	}
+	version, ok := versions[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not installed", name)
+	}
+	return b.readLocalFiles(name, version)
+}
+
+// readLocalFiles reads the %FILES% block of the local database entry for
+// name at version.
+func (b *ArchBackend) readLocalFiles(name, version string) ([]string, error) {
+	f, err := os.Open(filepath.Join(b.dbPath(), "local", name+"-"+version, "files"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return absPaths(parseDesc(f)["FILES"]), nil
+}
+
+// scanFilesDB calls fn with the file list of every package in a
+// <repo>.files archive until fn returns false.
+func scanFilesDB(path string, fn func(name, version string, files []string) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := openDBArchive(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Base(hdr.Name) != "files" {
+			continue
+		}
+		name, version, ok := splitNameVersion(filepath.Dir(hdr.Name))
+		if !ok {
+			continue
+		}
+		if !fn(name, version, absPaths(parseDesc(tr)["FILES"])) {
+			return nil
+		}
+	}
+}
+
+// absPaths turns the database's relative paths into absolute ones.
+func absPaths(files []string) []string {
+	abs := make([]string, len(files))
+	for i, f := range files {
+		abs[i] = "/" + f
+	}
+	return abs
+}
+
+func filesFromPacman(flag, target string) ([]string, error) {
+	cmd := exec.Command("pacman", flag, "--", target)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, errors.New(strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+
+	// -Ql prints absolute paths, -Fl relative ones.
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "/") {
+			line = "/" + line
+		}
+		files = append(files, line)
+	}
+	return files, nil
+}
+
+// FileOwners finds the packages that own or would install a file, like
+// pacman -Qo for installed packages and pacman -F for the rest. A query with
+// a slash is an exact path, anything else matches file names in any
+// directory.
+func FileOwners(ctx context.Context, query string) ([]Package, error) {
+	return backend.FileOwners(ctx, query)
+}
+
+// fileMatcher returns the test a file query applies to each path; fileIndex
+// owners matches the same way.
+func fileMatcher(query string) func(path string) bool {
+	if strings.Contains(query, "/") {
+		want := "/" + strings.Trim(query, "/")
+		return func(path string) bool {
+			return strings.TrimSuffix(path, "/") == want
+		}
+	}
+	return func(path string) bool {
+		return !strings.HasSuffix(path, "/") && filepath.Base(path) == query
+	}
+}
+
+func (b *ArchBackend) FileOwners(ctx context.Context, query string) ([]Package, error) {
+	installed, err := b.InstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Package, len(installed))
+	for _, p := range installed {
+		byName[p.Name] = p
+	}
+
+	var owners []Package
+	owned := make(map[string]bool)
+	add := func(name string) {
+		if p, ok := byName[name]; ok && !owned[name] {
+			owned[name] = true
+			owners = append(owners, p)
+		}
+	}
+
+	// Installed packages first.
+	if ix, err := b.localFileIndex(ctx); err == nil {
+		for _, name := range ix.owners(query) {
+			add(name)
+		}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else {
+		names, err := pacmanLines(ctx, "-Qoq", query)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			add(name)
+		}
+	}
+
+	// Then the sync files databases, for what isn't installed.
+	available, err := b.syncFileOwners(ctx, query, owned, func(path string) ([]string, error) {
+		ix, err := b.syncFileIndex(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return ix.owners(query), nil
+	})
+	if err != nil && !(errors.Is(err, errNoFilesDBs) && len(owners) > 0) {
+		return nil, err
+	}
+	return append(owners, available...), nil
+}
+
+var errNoFilesDBs = errors.New("no files databases found; run pacman -Fy")
+
+// SyncFileOwners is FileOwners without the local database: only the sync
+// files databases are read, which is all a command-not-found hook needs.
+// They are scanned once rather than indexed, as the hook only asks once.
+func (b *ArchBackend) SyncFileOwners(ctx context.Context, query string) ([]Package, error) {
+	match := fileMatcher(query)
+	return b.syncFileOwners(ctx, query, nil, func(path string) ([]string, error) {
+		var names []string
+		err := scanFilesDB(path, func(name, _ string, files []string) bool {
+			if slices.ContainsFunc(files, match) {
+				names = append(names, name)
+			}
+			return ctx.Err() == nil
+		})
+		return names, err
+	})
+}
+
+// syncFileOwners finds the owners of query in the sync files databases,
+// skipping the names in owned. ownersIn lists the owners in one <repo>.files
+// database.
+func (b *ArchBackend) syncFileOwners(ctx context.Context, query string, owned map[string]bool, ownersIn func(path string) ([]string, error)) ([]Package, error) {
+	var owners []Package
+	seen := make(map[string]bool)
+	add := func(p Package) {
+		if !owned[p.Name] && !seen[p.Name] {
+			seen[p.Name] = true
+			owners = append(owners, p)
+		}
+	}
+
+	remote, _ := b.syncPackages()
+	available := make(map[string]Package, len(remote))
+	for _, p := range remote {
+		available[p.Repository+"/"+p.Name] = p
+	}
+	repos, err := b.Repositories()
+	if err != nil {
+		return nil, err
+	}
+	missing := 0
+	for _, repo := range repos {
+		names, err := ownersIn(filepath.Join(b.dbPath(), "sync", repo+".files"))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			missing++
+			continue
+		}
+		if err != nil {
+			// Let pacman read databases we can't.
+			lines, err := pacmanLines(ctx, "-Fq", query)
+			if err != nil {
+				return nil, err
+			}
+			for _, line := range lines {
+				if p, ok := available[line]; ok {
+					add(p)
+				}
+			}
+			break
+		}
+		for _, name := range names {
+			if p, ok := available[repo+"/"+name]; ok {
+				add(p)
+			}
+		}
+	}
+	if len(owners) == 0 && len(repos) > 0 && missing == len(repos) {
+		return nil, errNoFilesDBs
+	}
+	return owners, nil
+}
+
+// fileIndex maps the paths in a database's file lists to the packages owning
+// them, so file: queries don't reread the lists on every keystroke.
+type fileIndex struct {
+	modTime time.Time
+	// byPath is keyed by path, without the trailing slash of directories.
+	byPath map[string][]string
+	// byBase is keyed by the base name of files; directories are left out.
+	byBase map[string][]string
+}
+
+func newFileIndex(modTime time.Time) *fileIndex {
+	return &fileIndex{modTime: modTime, byPath: make(map[string][]string), byBase: make(map[string][]string)}
+}
+
+func (ix *fileIndex) add(name string, files []string) {
+	for _, f := range files {
+		path := strings.TrimSuffix(f, "/")
+		ix.byPath[path] = append(ix.byPath[path], name)
+		if path == f {
+			base := filepath.Base(f)
+			ix.byBase[base] = append(ix.byBase[base], name)
+		}
+	}
+}
+
+// owners lists the packages owning query, matched like fileMatcher.
+func (ix *fileIndex) owners(query string) []string {
+	if strings.Contains(query, "/") {
+		return ix.byPath["/"+strings.Trim(query, "/")]
+	}
+	return ix.byBase[query]
+}
+
+// localFileIndex indexes the file lists of the installed packages, until the
+// local database changes. The index is built without holding the cache lock,
+// so other lookups aren't held up by it.
+func (b *ArchBackend) localFileIndex(ctx context.Context) (*fileIndex, error) {
+	info, err := os.Stat(filepath.Join(b.dbPath(), "local"))
+	if err != nil {
+		return nil, err
+	}
+	b.cache.mu.Lock()
+	ix := b.cache.localFiles
+	b.cache.mu.Unlock()
+	if ix != nil && ix.modTime.Equal(info.ModTime()) {
+		return ix, nil
+	}
+
+	local, err := b.localPackages()
+	if err != nil {
+		return nil, err
+	}
+	ix = newFileIndex(info.ModTime())
+	for _, p := range local {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if files, err := b.readLocalFiles(p.Name, p.Version); err == nil {
+			ix.add(p.Name, files)
+		}
+	}
+
+	b.cache.mu.Lock()
+	b.cache.localFiles = ix
+	b.cache.mu.Unlock()
+	return ix, nil
+}
+
+// syncFileIndex indexes a <repo>.files database until it changes on disk.
+func (b *ArchBackend) syncFileIndex(ctx context.Context, path string) (*fileIndex, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b.cache.mu.Lock()
+	ix := b.cache.files[path]
+	b.cache.mu.Unlock()
+	if ix != nil && ix.modTime.Equal(info.ModTime()) {
+		return ix, nil
+	}
+
+	ix = newFileIndex(info.ModTime())
+	err = scanFilesDB(path, func(name, _ string, files []string) bool {
+		ix.add(name, files)
+		return ctx.Err() == nil
+	})
+	if err == nil {
+		// Don't keep an index cut short.
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.cache.mu.Lock()
+	if b.cache.files == nil {
+		b.cache.files = make(map[string]*fileIndex)
+	}
+	b.cache.files[path] = ix
+	b.cache.mu.Unlock()
+	return ix, nil
+}
+
+// pacmanLines runs a pacman query and returns its output lines. pacman exits
+// 1 when nothing matches, which is not an error here.
+func pacmanLines(ctx context.Context, flag, target string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "pacman", flag, "--", target)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// cnfAURTimeout bounds the AUR lookup of CommandProviders, which runs while
+// the shell prompt waits.
+const cnfAURTimeout = 2 * time.Second
+
+// CommandProviders lists the packages that ship /usr/bin/<name>, for
+// command-not-found hooks. Only the sync files databases are read. They
+// don't cover the AUR, so with aur set and no repository package having the
+// command, an AUR package named after it is suggested instead.
+func CommandProviders(ctx context.Context, name string, aur bool) ([]Package, error) {
+	owners, err := backend.SyncFileOwners(ctx, "/usr/bin/"+name)
+	if err == nil && len(owners) > 0 {
+		markInstalled(owners)
+		return owners, nil
+	}
+	if !aur {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cnfAURTimeout)
+	defer cancel()
+	found, aurErr := backend.Resolve(ctx, []string{name})
+	if p, ok := found[name]; aurErr == nil && ok && p.IsAUR && p.Name == name {
+		pkgs := []Package{p}
+		markInstalled(pkgs)
+		return pkgs, nil
+	}
+	return nil, err
+}
+
+// markInstalled is checkInstalledStatus from the local versions alone,
+// without loading the whole installed inventory.
+func markInstalled(pkgs []Package) {
+	installed, err := backend.Installed()
+	if err != nil {
+		return
+	}
+	for i := range pkgs {
+		if version, ok := installed[pkgs[i].Name]; ok {
+			pkgs[i].IsInstalled = true
+			pkgs[i].InstalledVersion = version
+		}
+	}
+}
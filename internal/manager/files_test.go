@@ -0,0 +1,184 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFiles(t *testing.T) {
+	dbPath := newTestDB(t)
+	files := "%FILES%\nusr/\nusr/bin/\nusr/bin/bash\nusr/bin/sh\n\n%BACKUP%\netc/bash.bashrc\tabc\n"
+	if err := os.WriteFile(filepath.Join(dbPath, "local", "bash-5.2.026-2", "files"), []byte(files), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoArchive(t, filepath.Join(dbPath, "sync", "extra.files"), "files", map[string]string{
+		"glib2-2.80.0-1": "%FILES%\nusr/\nusr/bin/\nusr/bin/gio\nusr/lib/libglib-2.0.so\n",
+		"vim-9.1-1":      "%FILES%\nusr/bin/vim\n",
+	})
+	b := &ArchBackend{DBPath: dbPath}
+
+	got, err := b.Files(Package{Name: "bash", IsInstalled: true})
+	if err != nil {
+		t.Fatalf("Files(bash) returned error: %v", err)
+	}
+	if !slices.Equal(got, []string{"/usr/", "/usr/bin/", "/usr/bin/bash", "/usr/bin/sh"}) {
+		t.Errorf("Unexpected installed files %v", got)
+	}
+
+	got, err = b.Files(Package{Name: "glib2", Repository: "extra"})
+	if err != nil {
+		t.Fatalf("Files(glib2) returned error: %v", err)
+	}
+	if !slices.Equal(got, []string{"/usr/", "/usr/bin/", "/usr/bin/gio", "/usr/lib/libglib-2.0.so"}) {
+		t.Errorf("Unexpected files from the files database %v", got)
+	}
+
+	if _, err := b.Files(Package{Name: "yay-bin", IsAUR: true}); err == nil {
+		t.Error("Expected an error for an AUR package")
+	}
+}
+
+func TestFileOwners(t *testing.T) {
+	dbPath := newTestDB(t)
+	bashFiles := "%FILES%\nusr/\nusr/bin/\nusr/bin/bash\nusr/bin/sh\n"
+	if err := os.WriteFile(filepath.Join(dbPath, "local", "bash-5.2.026-2", "files"), []byte(bashFiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeRepoArchive(t, filepath.Join(dbPath, "sync", "core.files"), "files", map[string]string{
+		"bash-5.2.026-2": bashFiles,
+	})
+	writeRepoArchive(t, filepath.Join(dbPath, "sync", "extra.files"), "files", map[string]string{
+		"glib2-2.80.0-1": "%FILES%\nusr/\nusr/bin/\nusr/bin/gio\nusr/share/bash-completion/completions/gio\n",
+	})
+	b := &ArchBackend{DBPath: dbPath, ConfPath: filepath.Join(dbPath, "missing.conf")}
+	ctx := context.Background()
+
+	names := func(pkgs []Package) []string {
+		var out []string
+		for _, p := range pkgs {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	owners, err := b.FileOwners(ctx, "/usr/bin/bash")
+	if err != nil {
+		t.Fatalf("FileOwners() returned error: %v", err)
+	}
+	if !slices.Equal(names(owners), []string{"bash"}) || !owners[0].IsInstalled || owners[0].Repository != "core" {
+		t.Errorf("Expected the installed bash from core to own /usr/bin/bash, got %+v", owners)
+	}
+
+	owners, err = b.FileOwners(ctx, "gio")
+	if err != nil {
+		t.Fatalf("FileOwners() returned error: %v", err)
+	}
+	if !slices.Equal(names(owners), []string{"glib2"}) || owners[0].IsInstalled || owners[0].Repository != "extra" {
+		t.Errorf("Expected glib2 from the extra files database for gio, got %+v", owners)
+	}
+
+	owners, err = b.FileOwners(ctx, "usr/share/bash-completion/")
+	if err != nil || !slices.Equal(names(owners), []string(nil)) {
+		t.Errorf("Expected no owner of a directory nobody lists, got %v (%v)", names(owners), err)
+	}
+
+	// The sync files databases alone don't know what is installed.
+	owners, err = b.SyncFileOwners(ctx, "/usr/bin/bash")
+	if err != nil || !slices.Equal(names(owners), []string{"bash"}) || owners[0].IsInstalled {
+		t.Errorf("Expected bash from the core files database, got %+v (%v)", owners, err)
+	}
+}
+
+func TestFileOwnersIndexCache(t *testing.T) {
+	dbPath := newTestDB(t)
+	path := filepath.Join(dbPath, "sync", "extra.files")
+	writeRepoArchive(t, path, "files", map[string]string{
+		"glib2-2.80.0-1": "%FILES%\nusr/bin/gio\n",
+	})
+	b := &ArchBackend{DBPath: dbPath, ConfPath: filepath.Join(dbPath, "missing.conf")}
+	ctx := context.Background()
+
+	if owners, err := b.FileOwners(ctx, "gio"); err != nil || len(owners) != 1 {
+		t.Fatalf("Expected glib2 to own gio, got %+v (%v)", owners, err)
+	}
+
+	// Same modification time: the index isn't rebuilt.
+	info, _ := os.Stat(path)
+	writeRepoArchive(t, path, "files", map[string]string{
+		"glib2-2.80.0-1": "%FILES%\nusr/bin/gio-new\n",
+	})
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if owners, err := b.FileOwners(ctx, "gio"); err != nil || len(owners) != 1 {
+		t.Errorf("Expected the cached index, got %+v (%v)", owners, err)
+	}
+
+	later := info.ModTime().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if owners, err := b.FileOwners(ctx, "gio"); err != nil || len(owners) != 0 {
+		t.Errorf("Expected the index to follow the changed database, got %+v (%v)", owners, err)
+	}
+	if owners, err := b.FileOwners(ctx, "gio-new"); err != nil || len(owners) != 1 {
+		t.Errorf("Expected glib2 to own gio-new, got %+v (%v)", owners, err)
+	}
+}
+
+func TestSearchFilePrefix(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "vim", Repository: "extra"},
+		Package{Name: "gvim", Repository: "extra", IsInstalled: true},
+	)
+	fake.FileLists = map[string][]string{
+		"vim":  {"/usr/", "/usr/bin/", "/usr/bin/vim"},
+		"gvim": {"/usr/", "/usr/bin/", "/usr/bin/vim", "/usr/bin/gvim"},
+	}
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	pkgs, err := SearchContext(context.Background(), "file: /usr/bin/vim")
+	if err != nil {
+		t.Fatalf("SearchContext() returned error: %v", err)
+	}
+	if len(pkgs) != 2 || pkgs[0].Name != "vim" || !pkgs[1].IsInstalled {
+		t.Errorf("Expected vim and the installed gvim, got %+v", pkgs)
+	}
+}
+
+func TestCommandProviders(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "htop", Repository: "extra", Version: "3.3.0-1"},
+		Package{Name: "yay", IsAUR: true, Version: "12.3.5-1"},
+	)
+	fake.FileLists = map[string][]string{"htop": {"/usr/", "/usr/bin/", "/usr/bin/htop"}}
+	SetBackend(fake)
+	defer SetBackend(nil)
+	ctx := context.Background()
+
+	pkgs, err := CommandProviders(ctx, "htop", false)
+	if err != nil || len(pkgs) != 1 || pkgs[0].QualifiedName() != "extra/htop" {
+		t.Errorf("Expected extra/htop to ship htop, got %+v (%v)", pkgs, err)
+	}
+	cacheMu.RLock()
+	loaded := installedPkgs != nil
+	cacheMu.RUnlock()
+	if loaded {
+		t.Error("Expected the hook not to load the installed inventory")
+	}
+
+	// The files databases don't know the AUR, so fall back to the name,
+	// but only when asked to.
+	if pkgs, err := CommandProviders(ctx, "yay", false); err != nil || len(pkgs) != 0 {
+		t.Errorf("Expected no AUR lookup by default, got %+v (%v)", pkgs, err)
+	}
+	pkgs, err = CommandProviders(ctx, "yay", true)
+	if err != nil || len(pkgs) != 1 || !pkgs[0].IsAUR {
+		t.Errorf("Expected the AUR yay to be suggested, got %+v (%v)", pkgs, err)
+	}
+
+	if pkgs, err := CommandProviders(ctx, "nope", true); err != nil || len(pkgs) != 0 {
+		t.Errorf("Expected no providers for nope, got %+v (%v)", pkgs, err)
+	}
+}
@@ -0,0 +1,340 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// GraphOptions controls which edges BuildGraph follows.
+type GraphOptions struct {
+	// Depth limits how far from the roots the graph goes; 0 means no limit.
+	Depth int
+	// Reverse follows RequiredBy (and OptionalFor) instead of Depends.
+	// Reverse graphs only cover installed packages.
+	Reverse     bool
+	OptDepends  bool
+	MakeDepends bool
+}
+
+// Edge kinds.
+const (
+	EdgeDepends     = "depends"
+	EdgeOptDepends  = "optdepends"
+	EdgeMakeDepends = "makedepends"
+)
+
+type GraphEdge struct {
+	From, To string
+	Kind     string
+}
+
+// Graph is a dependency graph with nodes in the order they were reached.
+type Graph struct {
+	Nodes  []string
+	Status map[string]DepStatus
+	Edges  []GraphEdge
+	// AURErr is set when the AUR couldn't be asked; what only the AUR has
+	// is then a missing node.
+	AURErr error
+}
+
+func (g *Graph) addNode(name string, status DepStatus) bool {
+	if _, ok := g.Status[name]; ok {
+		return false
+	}
+	g.Nodes = append(g.Nodes, name)
+	g.Status[name] = status
+	return true
+}
+
+// BuildGraph builds the dependency graph of the named packages, or of every
+// installed package when names is empty. An unreachable AUR doesn't fail it;
+// see Graph.AURErr.
+func BuildGraph(ctx context.Context, names []string, opts GraphOptions) (*Graph, error) {
+	if len(names) == 0 {
+		return systemGraph(opts), nil
+	}
+	if opts.Reverse {
+		return reverseGraph(names, opts)
+	}
+
+	g := &Graph{Status: map[string]DepStatus{}}
+	var level []Package
+	for _, name := range names {
+		found, err := g.resolve(ctx, []string{name})
+		if err != nil {
+			return nil, err
+		}
+		p, ok := found[name]
+		if !ok && g.AURErr == nil {
+			return nil, fmt.Errorf("package %s not found", name)
+		}
+		if !ok {
+			g.addNode(name, DepMissing)
+			continue
+		}
+		if g.addNode(p.Name, statusOf(p)) {
+			level = append(level, p)
+		}
+	}
+
+	for depth := 0; len(level) > 0 && (opts.Depth == 0 || depth < opts.Depth); depth++ {
+		type dep struct {
+			from, name, kind string
+		}
+		var deps []dep
+		var wanted []string
+		for _, p := range level {
+			for _, d := range graphDeps(p, opts) {
+				deps = append(deps, dep{p.Name, d[0], d[1]})
+				if !slices.Contains(wanted, d[0]) {
+					wanted = append(wanted, d[0])
+				}
+			}
+		}
+		if len(wanted) == 0 {
+			break
+		}
+		found, err := g.resolve(ctx, wanted)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []Package
+		for _, d := range deps {
+			p, ok := found[d.name]
+			to := d.name
+			status := DepMissing
+			if ok {
+				to, status = p.Name, statusOf(p)
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: d.from, To: to, Kind: d.kind})
+			if g.addNode(to, status) && ok {
+				next = append(next, p)
+			}
+		}
+		level = next
+	}
+	return g, nil
+}
+
+// resolve is backend.Resolve that keeps what was satisfied without the AUR
+// when only the AUR failed, noting its error in AURErr.
+func (g *Graph) resolve(ctx context.Context, names []string) (map[string]Package, error) {
+	found, err := backend.Resolve(ctx, names)
+	switch {
+	case err == nil:
+	case found == nil || ctx.Err() != nil:
+		return nil, err
+	default:
+		g.AURErr = err
+	}
+	return found, nil
+}
+
+// graphDeps lists p's dependency names and edge kinds under opts.
+func graphDeps(p Package, opts GraphOptions) [][2]string {
+	var deps [][2]string
+	for _, d := range p.Depends {
+		deps = append(deps, [2]string{depName(d), EdgeDepends})
+	}
+	if opts.OptDepends {
+		for _, d := range p.OptDepends {
+			deps = append(deps, [2]string{optDepName(d), EdgeOptDepends})
+		}
+	}
+	if opts.MakeDepends {
+		for _, d := range p.MakeDepends {
+			deps = append(deps, [2]string{depName(d), EdgeMakeDepends})
+		}
+	}
+	return deps
+}
+
+// optDepName strips the description from "name: description" and any
+// version constraint.
+func optDepName(dep string) string {
+	name, _, _ := strings.Cut(dep, ":")
+	return depName(strings.TrimSpace(name))
+}
+
+// reverseGraph follows what requires the named installed packages.
+func reverseGraph(names []string, opts GraphOptions) (*Graph, error) {
+	installed := InstalledPackages()
+	byName := make(map[string]Package, len(installed))
+	for _, p := range installed {
+		byName[p.Name] = p
+	}
+
+	g := &Graph{Status: map[string]DepStatus{}}
+	var level []Package
+	for _, name := range names {
+		p, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is not installed", name)
+		}
+		if g.addNode(name, DepInstalled) {
+			level = append(level, p)
+		}
+	}
+
+	for depth := 0; len(level) > 0 && (opts.Depth == 0 || depth < opts.Depth); depth++ {
+		var next []Package
+		for _, p := range level {
+			edges := map[string][]string{EdgeDepends: requiredBy(p, installed)}
+			if opts.OptDepends {
+				edges[EdgeOptDepends] = optionalFor(p, installed)
+			}
+			for _, kind := range []string{EdgeDepends, EdgeOptDepends} {
+				for _, parent := range edges[kind] {
+					g.Edges = append(g.Edges, GraphEdge{From: parent, To: p.Name, Kind: kind})
+					if g.addNode(parent, DepInstalled) {
+						next = append(next, byName[parent])
+					}
+				}
+			}
+		}
+		level = next
+	}
+	return g, nil
+}
+
+// optionalFor lists the packages in pool that optionally depend on target.
+func optionalFor(target Package, pool []Package) []string {
+	satisfies := map[string]bool{target.Name: true}
+	for _, prov := range target.Provides {
+		satisfies[depName(prov)] = true
+	}
+	var names []string
+	for _, p := range pool {
+		for _, dep := range p.OptDepends {
+			if satisfies[optDepName(dep)] {
+				names = append(names, p.Name)
+				break
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// systemGraph links every installed package to the installed packages that
+// satisfy its dependencies.
+func systemGraph(opts GraphOptions) *Graph {
+	installed := InstalledPackages()
+	g := &Graph{Status: map[string]DepStatus{}}
+	for _, p := range installed {
+		g.addNode(p.Name, DepInstalled)
+	}
+
+	var names []string
+	for _, p := range installed {
+		for _, d := range graphDeps(p, opts) {
+			names = append(names, d[0])
+		}
+	}
+	found := resolveFrom(names, installed, nil)
+	for _, p := range installed {
+		for _, d := range graphDeps(p, opts) {
+			if dep, ok := found[d[0]]; ok {
+				g.Edges = append(g.Edges, GraphEdge{From: p.Name, To: dep.Name, Kind: d[1]})
+			}
+		}
+	}
+	return g
+}
+
+// WriteDOT writes g in Graphviz DOT format.
+func WriteDOT(w io.Writer, g *Graph) error {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box, style=rounded];\n")
+	for _, n := range g.Nodes {
+		attrs := ""
+		switch g.Status[n] {
+		case DepRepo:
+			attrs = ", color=blue"
+		case DepAUR:
+			attrs = ", color=orange"
+		case DepMissing:
+			attrs = ", color=red, style=dashed"
+		}
+		fmt.Fprintf(&sb, "\t%q [label=%q%s];\n", n, n, attrs)
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		switch e.Kind {
+		case EdgeOptDepends:
+			attrs = " [style=dashed, label=\"optional\"]"
+		case EdgeMakeDepends:
+			attrs = " [style=dotted, label=\"make\"]"
+		}
+		fmt.Fprintf(&sb, "\t%q -> %q%s;\n", e.From, e.To, attrs)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteMermaid writes g as a Mermaid flowchart. Package names can contain
+// characters Mermaid doesn't allow in ids, so nodes get numbered ids.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	ids := make(map[string]string, len(g.Nodes))
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	for i, n := range g.Nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&sb, "    %s[\"%s\"]\n", ids[n], strings.ReplaceAll(n, `"`, "#quot;"))
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		switch e.Kind {
+		case EdgeOptDepends:
+			arrow = "-. optional .->"
+		case EdgeMakeDepends:
+			arrow = "-. make .->"
+		}
+		fmt.Fprintf(&sb, "    %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+	for _, class := range []struct {
+		status DepStatus
+		name   string
+		style  string
+	}{
+		{DepRepo, "repo", "stroke:#458588"},
+		{DepAUR, "aur", "stroke:#d65d0e"},
+		{DepMissing, "missing", "stroke:#cc241d,stroke-dasharray:4"},
+	} {
+		var members []string
+		for _, n := range g.Nodes {
+			if g.Status[n] == class.status {
+				members = append(members, ids[n])
+			}
+		}
+		if len(members) > 0 {
+			fmt.Fprintf(&sb, "    classDef %s %s\n", class.name, class.style)
+			fmt.Fprintf(&sb, "    class %s %s\n", strings.Join(members, ","), class.name)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// GraphFormats are the formats WriteGraph understands.
+var GraphFormats = []string{"dot", "mermaid"}
+
+// WriteGraph writes g in the named format.
+func WriteGraph(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case "dot":
+		return WriteDOT(w, g)
+	case "mermaid":
+		return WriteMermaid(w, g)
+	default:
+		return fmt.Errorf("unknown graph format %q (want %s)", format, strings.Join(GraphFormats, " or "))
+	}
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "app", Repository: "extra", Depends: []string{"libfoo>=1.0", "ghost"},
+			OptDepends: []string{"extras: more features"}, MakeDepends: []string{"cmake"}},
+		Package{Name: "libfoo", Repository: "extra", Depends: []string{"glibc"}},
+		Package{Name: "glibc", Repository: "core", IsInstalled: true},
+		Package{Name: "extras", IsAUR: true},
+		Package{Name: "cmake", Repository: "extra"},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	g, err := BuildGraph(context.Background(), []string{"app"}, GraphOptions{})
+	if err != nil {
+		t.Fatalf("BuildGraph() returned error: %v", err)
+	}
+	if !slices.Equal(g.Nodes, []string{"app", "libfoo", "ghost", "glibc"}) {
+		t.Errorf("Unexpected nodes %v", g.Nodes)
+	}
+	if g.Status["ghost"] != DepMissing || g.Status["glibc"] != DepInstalled {
+		t.Errorf("Unexpected statuses %v", g.Status)
+	}
+
+	g, err = BuildGraph(context.Background(), []string{"app"}, GraphOptions{Depth: 1, OptDepends: true, MakeDepends: true})
+	if err != nil {
+		t.Fatalf("BuildGraph() returned error: %v", err)
+	}
+	if !slices.Equal(g.Nodes, []string{"app", "libfoo", "ghost", "extras", "cmake"}) {
+		t.Errorf("Expected one level with optional and make dependencies, got %v", g.Nodes)
+	}
+	if e := g.Edges[2]; e.To != "extras" || e.Kind != EdgeOptDepends {
+		t.Errorf("Expected an optional edge to extras, got %+v", e)
+	}
+
+	if _, err := BuildGraph(context.Background(), []string{"nope"}, GraphOptions{}); err == nil {
+		t.Error("Expected an error for an unknown package")
+	}
+}
+
+func TestBuildGraphAUROffline(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "app", Repository: "extra", Depends: []string{"libfoo", "aur-lib"}},
+		Package{Name: "libfoo", Repository: "extra"},
+		Package{Name: "aur-lib", IsAUR: true},
+	)
+	fake.SourceErrs = map[SearchSource]error{SourceAUR: errors.New("rate limited")}
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	g, err := BuildGraph(context.Background(), []string{"app"}, GraphOptions{})
+	if err != nil {
+		t.Fatalf("Expected the graph without the AUR, got %v", err)
+	}
+	if g.AURErr == nil || !slices.Equal(g.Nodes, []string{"app", "libfoo", "aur-lib"}) {
+		t.Errorf("Expected repo nodes and a warning, got %v (%v)", g.Nodes, g.AURErr)
+	}
+	if g.Status["libfoo"] == DepMissing || g.Status["aur-lib"] != DepMissing {
+		t.Errorf("Expected only aur-lib to be missing, got %v", g.Status)
+	}
+}
+
+func TestBuildGraphReverseAndSystem(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "firefox", Repository: "extra", IsInstalled: true, Depends: []string{"gtk3"}, OptDepends: []string{"ffmpeg: video"}},
+		Package{Name: "gtk3", Repository: "extra", IsInstalled: true, Dependency: true, Depends: []string{"glib2"}},
+		Package{Name: "glib2", Repository: "core", IsInstalled: true, Dependency: true},
+		Package{Name: "ffmpeg", Repository: "extra", IsInstalled: true, Depends: []string{"glib2"}},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	g, err := BuildGraph(context.Background(), []string{"glib2"}, GraphOptions{Reverse: true})
+	if err != nil {
+		t.Fatalf("BuildGraph() returned error: %v", err)
+	}
+	if !slices.Equal(g.Nodes, []string{"glib2", "ffmpeg", "gtk3", "firefox"}) {
+		t.Errorf("Unexpected reverse nodes %v", g.Nodes)
+	}
+	if !slices.Contains(g.Edges, GraphEdge{From: "gtk3", To: "glib2", Kind: EdgeDepends}) {
+		t.Errorf("Expected gtk3 -> glib2, got %v", g.Edges)
+	}
+
+	g, err = BuildGraph(context.Background(), []string{"ffmpeg"}, GraphOptions{Reverse: true, OptDepends: true})
+	if err != nil {
+		t.Fatalf("BuildGraph() returned error: %v", err)
+	}
+	if !slices.Equal(g.Edges, []GraphEdge{{From: "firefox", To: "ffmpeg", Kind: EdgeOptDepends}}) {
+		t.Errorf("Expected firefox to optionally require ffmpeg, got %v", g.Edges)
+	}
+
+	g, err = BuildGraph(context.Background(), nil, GraphOptions{})
+	if err != nil {
+		t.Fatalf("BuildGraph() returned error: %v", err)
+	}
+	if len(g.Nodes) != 4 || len(g.Edges) != 3 {
+		t.Errorf("Expected 4 installed packages and 3 edges, got %v and %v", g.Nodes, g.Edges)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	g := &Graph{
+		Nodes:  []string{"app", "libc++", "ghost"},
+		Status: map[string]DepStatus{"app": DepInstalled, "libc++": DepRepo, "ghost": DepMissing},
+		Edges: []GraphEdge{
+			{From: "app", To: "libc++", Kind: EdgeDepends},
+			{From: "app", To: "ghost", Kind: EdgeOptDepends},
+		},
+	}
+
+	var dot strings.Builder
+	if err := WriteGraph(&dot, g, "dot"); err != nil {
+		t.Fatalf("WriteGraph(dot) returned error: %v", err)
+	}
+	for _, want := range []string{"digraph dependencies {", `"app" -> "libc++";`, `"app" -> "ghost" [style=dashed`, `"ghost" [label="ghost", color=red`} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("Expected %q in DOT output:\n%s", want, dot.String())
+		}
+	}
+
+	var mermaid strings.Builder
+	if err := WriteGraph(&mermaid, g, "mermaid"); err != nil {
+		t.Fatalf("WriteGraph(mermaid) returned error: %v", err)
+	}
+	for _, want := range []string{"flowchart LR", `n1["libc++"]`, "n0 --> n1", "n0 -. optional .-> n2", "class n2 missing"} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("Expected %q in Mermaid output:\n%s", want, mermaid.String())
+		}
+	}
+
+	if err := WriteGraph(&dot, g, "svg"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// HistoryEntry is one package change recorded in pacman.log.
+type HistoryEntry struct {
+	Time       time.Time
+	Action     string // installed, upgraded, downgraded, reinstalled or removed
+	Package    string
+	OldVersion string
+	NewVersion string
+}
+
+// HistoryTransaction groups the entries between "transaction started" and
+// "transaction completed", together with the command that started it.
+type HistoryTransaction struct {
+	Start   time.Time
+	Command string
+	Entries []HistoryEntry
+}
+
+var historyActions = []string{"installed", "upgraded", "downgraded", "reinstalled", "removed"}
+
+// ParseHistory reads a pacman.log into transactions, oldest first.
+func ParseHistory(r io.Reader) ([]HistoryTransaction, error) {
+	var (
+		txs     []HistoryTransaction
+		current *HistoryTransaction
+		command string
+	)
+	flush := func() {
+		if current != nil && len(current.Entries) > 0 {
+			txs = append(txs, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		ts, ok := parseLogTime(line)
+		if !ok {
+			continue
+		}
+		msg := strings.TrimSpace(line[strings.Index(line, "]")+1:])
+
+		// Since pacman 5.1 every message is tagged with its origin.
+		tag := ""
+		if strings.HasPrefix(msg, "[") {
+			if end := strings.Index(msg, "]"); end > 0 {
+				tag = msg[1:end]
+				msg = strings.TrimSpace(msg[end+1:])
+			}
+		}
+
+		switch {
+		case tag == "PACMAN" && strings.HasPrefix(msg, "Running '"):
+			command = strings.TrimSuffix(strings.TrimPrefix(msg, "Running '"), "'")
+		case msg == "transaction started":
+			flush()
+			current = &HistoryTransaction{Start: ts, Command: command}
+			command = ""
+		case msg == "transaction completed" || msg == "transaction failed":
+			flush()
+		case tag == "" || tag == "ALPM":
+			entry, ok := parseHistoryEntry(msg)
+			if !ok {
+				continue
+			}
+			entry.Time = ts
+			// Logs older than pacman 4 have no transaction markers.
+			if current == nil {
+				current = &HistoryTransaction{Start: ts, Command: command}
+				command = ""
+			}
+			current.Entries = append(current.Entries, entry)
+		}
+	}
+	flush()
+	return txs, scanner.Err()
+}
+
+// parseHistoryEntry parses "upgraded foo (1.0-1 -> 1.1-1)" and friends.
+func parseHistoryEntry(msg string) (HistoryEntry, bool) {
+	action, rest, ok := strings.Cut(msg, " ")
+	if !ok {
+		return HistoryEntry{}, false
+	}
+	if !slices.Contains(historyActions, action) {
+		return HistoryEntry{}, false
+	}
+
+	name, versions, ok := strings.Cut(rest, " ")
+	if !ok || !strings.HasPrefix(versions, "(") || !strings.HasSuffix(versions, ")") {
+		return HistoryEntry{}, false
+	}
+	versions = versions[1 : len(versions)-1]
+
+	entry := HistoryEntry{Action: action, Package: name}
+	if oldVer, newVer, ok := strings.Cut(versions, " -> "); ok {
+		entry.OldVersion, entry.NewVersion = oldVer, newVer
+	} else if action == "removed" {
+		entry.OldVersion = versions
+	} else {
+		entry.NewVersion = versions
+	}
+	return entry, true
+}
+
+// History parses the backend's pacman.log.
+func History() ([]HistoryTransaction, error) {
+	log, err := backend.PacmanLog()
+	if err != nil {
+		return nil, err
+	}
+	defer log.Close()
+	return ParseHistory(log)
+}
+
+// HistoryFilter narrows history down to a package and a date range. Zero
+// values match everything; Until is inclusive of the whole day it names.
+type HistoryFilter struct {
+	Package string
+	Since   time.Time
+	Until   time.Time
+}
+
+// ParseHistoryFilter reads "name since:2024-01-01 until:2024-02-01"; any
+// term without a prefix is matched against package names.
+func ParseHistoryFilter(query string) (HistoryFilter, error) {
+	var f HistoryFilter
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || (key != "since" && key != "until") {
+			terms = append(terms, field)
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return f, fmt.Errorf("%s: expected a date like 2024-01-31, got %q", key, value)
+		}
+		if key == "since" {
+			f.Since = t
+		} else {
+			f.Until = t.AddDate(0, 0, 1)
+		}
+	}
+	f.Package = strings.Join(terms, " ")
+	return f, nil
+}
+
+func (f HistoryFilter) Matches(e HistoryEntry) bool {
+	if f.Package != "" && !strings.Contains(strings.ToLower(e.Package), strings.ToLower(f.Package)) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
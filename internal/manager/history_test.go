@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLog = `[2012-01-05 10:00] installed oldpkg (1.0-1)
+[2024-07-01T12:00:00+0000] [PACMAN] Running 'pacman -Syu'
+[2024-07-01T12:00:01+0000] [PACMAN] synchronizing package lists
+[2024-07-01T12:00:05+0000] [ALPM] transaction started
+[2024-07-01T12:00:06+0000] [ALPM] upgraded bash (5.2.026-1 -> 5.2.026-2)
+[2024-07-01T12:00:06+0000] [ALPM] installed libnew (1.0-1)
+[2024-07-01T12:00:07+0000] [ALPM-SCRIPTLET] ==> Running hook
+[2024-07-01T12:00:08+0000] [ALPM] transaction completed
+[2024-07-03T09:00:00+0000] [PACMAN] Running 'pacman -U /var/cache/pacman/pkg/bash-5.2.026-1-x86_64.pkg.tar.zst'
+[2024-07-03T09:00:01+0000] [ALPM] transaction started
+[2024-07-03T09:00:02+0000] [ALPM] downgraded bash (5.2.026-2 -> 5.2.026-1)
+[2024-07-03T09:00:03+0000] [ALPM] transaction completed
+[2024-07-04T09:00:00+0000] [PACMAN] Running 'pacman -Rns libnew'
+[2024-07-04T09:00:01+0000] [ALPM] transaction started
+[2024-07-04T09:00:02+0000] [ALPM] removed libnew (1.0-1)
+[2024-07-04T09:00:03+0000] [ALPM] transaction completed
+[2024-07-05T09:00:01+0000] [ALPM] transaction started
+[2024-07-05T09:00:03+0000] [ALPM] transaction failed
+`
+
+func TestParseHistory(t *testing.T) {
+	txs, err := ParseHistory(strings.NewReader(testLog))
+	if err != nil {
+		t.Fatalf("ParseHistory() returned error: %v", err)
+	}
+	if len(txs) != 4 {
+		t.Fatalf("Expected 4 transactions, got %d: %+v", len(txs), txs)
+	}
+
+	if e := txs[0].Entries[0]; e.Action != "installed" || e.Package != "oldpkg" || e.NewVersion != "1.0-1" {
+		t.Errorf("Unexpected entry from an old-style log: %+v", e)
+	}
+
+	upgrade := txs[1]
+	if upgrade.Command != "pacman -Syu" || len(upgrade.Entries) != 2 {
+		t.Fatalf("Unexpected upgrade transaction: %+v", upgrade)
+	}
+	if e := upgrade.Entries[0]; e.Action != "upgraded" || e.OldVersion != "5.2.026-1" || e.NewVersion != "5.2.026-2" {
+		t.Errorf("Unexpected upgrade entry: %+v", e)
+	}
+	if !upgrade.Start.Equal(time.Date(2024, 7, 1, 12, 0, 5, 0, time.UTC)) {
+		t.Errorf("Unexpected transaction start %v", upgrade.Start)
+	}
+
+	if e := txs[2].Entries[0]; e.Action != "downgraded" || e.NewVersion != "5.2.026-1" {
+		t.Errorf("Unexpected downgrade entry: %+v", e)
+	}
+	if e := txs[3].Entries[0]; e.Action != "removed" || e.OldVersion != "1.0-1" || e.NewVersion != "" {
+		t.Errorf("Unexpected removal entry: %+v", e)
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	txs, _ := ParseHistory(strings.NewReader(testLog))
+
+	count := func(query string) int {
+		t.Helper()
+		f, err := ParseHistoryFilter(query)
+		if err != nil {
+			t.Fatalf("ParseHistoryFilter(%q) returned error: %v", query, err)
+		}
+		n := 0
+		for _, tx := range txs {
+			for _, e := range tx.Entries {
+				if f.Matches(e) {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	if n := count("BASH"); n != 2 {
+		t.Errorf("Expected 2 bash entries, got %d", n)
+	}
+	if n := count("since:2024-07-02"); n != 2 {
+		t.Errorf("Expected 2 entries since 2024-07-02, got %d", n)
+	}
+	if n := count("bash until:2024-07-01"); n != 1 {
+		t.Errorf("Expected 1 bash entry until 2024-07-01, got %d", n)
+	}
+
+	if _, err := ParseHistoryFilter("since:yesterday"); err == nil || !strings.Contains(err.Error(), "since") {
+		t.Errorf("Expected a since: error, got %v", err)
+	}
+}
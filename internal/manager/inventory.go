@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// InstalledPackages returns the installed packages last read by
+// LoadInstalledPackages, reading them first if nothing has yet.
+func InstalledPackages() []Package {
+	cacheMu.RLock()
+	pkgs := installedPkgs
+	cacheMu.RUnlock()
+	if pkgs == nil {
+		pkgs, _ = LoadInstalledPackages()
+	}
+	return append([]Package(nil), pkgs...)
+}
+
+// LoadInstalledPackages reads every installed package afresh and keeps them
+// for InstalledPackages. It parses the whole local and sync databases, so
+// callers that only need versions should use GetInstalledCache.
+func LoadInstalledPackages() ([]Package, error) {
+	pkgs, err := backend.InstalledPackages()
+	if err != nil {
+		return nil, err
+	}
+	cacheMu.Lock()
+	installedPkgs = pkgs
+	cacheMu.Unlock()
+	return append([]Package(nil), pkgs...), nil
+}
+
+// InstalledPackages lists the local database, marking packages that no sync
+// repository has as Foreign and tagging the rest with their repository and
+// the version it offers.
+func (b *ArchBackend) InstalledPackages() ([]Package, error) {
+	local, err := b.localPackages()
+	if err != nil {
+		return installedFromPacman()
+	}
+	remote, _ := b.syncPackages()
+
+	available := make(map[string]Package, len(remote))
+	for _, p := range remote {
+		if _, ok := available[p.Name]; !ok {
+			available[p.Name] = p
+		}
+	}
+
+	pkgs := make([]Package, len(local))
+	for i, p := range local {
+		p.InstalledVersion = p.Version
+		if sp, ok := available[p.Name]; ok {
+			p.Repository = sp.Repository
+			p.Version = sp.Version
+		} else {
+			p.Repository = "local"
+			p.Foreign = true
+		}
+		pkgs[i] = p
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+	return pkgs, nil
+}
+
+// installedFromPacman builds the inventory from pacman -Q, -Qdq and -Qmq when
+// the local database can't be read directly. Sizes are unknown this way.
+func installedFromPacman() ([]Package, error) {
+	query := func(flag string) ([]byte, error) {
+		cmd := exec.Command("pacman", flag)
+		cmd.Env = append(os.Environ(), "LC_ALL=C")
+		out, err := cmd.Output()
+		// pacman exits 1 when a filter matches nothing.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && len(out) == 0 {
+			return nil, nil
+		}
+		return out, err
+	}
+	names := func(flag string) (map[string]bool, error) {
+		out, err := query(flag)
+		set := make(map[string]bool)
+		for _, name := range strings.Fields(string(out)) {
+			set[name] = true
+		}
+		return set, err
+	}
+
+	out, err := query("-Q")
+	if err != nil {
+		return nil, err
+	}
+	deps, err := names("-Qdq")
+	if err != nil {
+		return nil, err
+	}
+	foreign, err := names("-Qmq")
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name, version, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Name:             name,
+			Version:          version,
+			InstalledVersion: version,
+			Repository:       "local",
+			IsInstalled:      true,
+			Dependency:       deps[name],
+			Foreign:          foreign[name],
+		})
+	}
+	return pkgs, scanner.Err()
+}
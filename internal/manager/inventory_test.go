@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestInstalledPackagesFromDatabase(t *testing.T) {
+	dbPath := newTestDB(t)
+	b := &ArchBackend{DBPath: dbPath, ConfPath: filepath.Join(dbPath, "missing.conf")}
+
+	pkgs, err := b.InstalledPackages()
+	if err != nil {
+		t.Fatalf("InstalledPackages() returned error: %v", err)
+	}
+	if len(pkgs) != 3 {
+		t.Fatalf("Expected 3 installed packages, got %d", len(pkgs))
+	}
+
+	byName := make(map[string]Package)
+	for _, p := range pkgs {
+		byName[p.Name] = p
+	}
+	if glibc := byName["glibc"]; glibc.Repository != "core" || glibc.InstalledVersion != "2.40-1" || glibc.Foreign || !glibc.Dependency {
+		t.Errorf("Expected glibc to be a native dependency from core, got %+v", glibc)
+	}
+	if yay := byName["yay"]; yay.Repository != "local" || !yay.Foreign {
+		t.Errorf("Expected yay to be foreign, got %+v", yay)
+	}
+	if pkgs[0].Name != "bash" {
+		t.Errorf("Expected packages sorted by name, got %s first", pkgs[0].Name)
+	}
+}
+
+func TestInstalledPackagesCache(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "bash", Repository: "core", IsInstalled: true},
+		Package{Name: "yay", IsAUR: true, IsInstalled: true},
+		Package{Name: "vim", Repository: "extra"},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	pkgs := InstalledPackages()
+	if len(pkgs) != 2 || pkgs[0].Name != "bash" || !pkgs[1].Foreign {
+		t.Fatalf("Unexpected inventory %+v", pkgs)
+	}
+
+	fake.Command(Transaction{Install: []string{"extra/vim"}})
+	if len(InstalledPackages()) != 2 {
+		t.Error("Expected the inventory to stay cached until refreshed")
+	}
+	RefreshInstalledCache()
+	if len(InstalledPackages()) != 2 {
+		t.Error("Expected refreshing the versions not to reload the inventory")
+	}
+	if pkgs, err := LoadInstalledPackages(); err != nil || len(pkgs) != 3 || len(InstalledPackages()) != 3 {
+		t.Errorf("Expected vim in the inventory after a reload, got %+v (%v)", pkgs, err)
+	}
+}
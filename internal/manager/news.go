@@ -0,0 +1,156 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultNewsURL = "https://archlinux.org/feeds/news/"
+
+var newsURL = defaultNewsURL
+
+// SetNewsURL overrides the Arch news feed, e.g. to point at a mirror or a
+// local test server. An empty string restores the default.
+func SetNewsURL(u string) {
+	if u == "" {
+		u = defaultNewsURL
+	}
+	newsURL = u
+}
+
+type NewsItem struct {
+	Title              string
+	Link               string
+	Description        string
+	Published          time.Time
+	ManualIntervention bool
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// FetchNews downloads and parses the news RSS feed, newest first.
+func FetchNews(ctx context.Context) ([]NewsItem, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", newsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, netError(err)
+	}
+	defer resp.Body.Close()
+
+	if err := statusError(resp, "news"); err != nil {
+		return nil, err
+	}
+	return parseNews(resp.Body)
+}
+
+func parseNews(r io.Reader) ([]NewsItem, error) {
+	var feed struct {
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+
+	var items []NewsItem
+	for _, it := range feed.Items {
+		desc := html.UnescapeString(htmlTag.ReplaceAllString(it.Description, ""))
+		item := NewsItem{
+			Title:       strings.TrimSpace(it.Title),
+			Link:        strings.TrimSpace(it.Link),
+			Description: strings.TrimSpace(desc),
+		}
+		for _, layout := range []string{time.RFC1123Z, time.RFC1123} {
+			if t, err := time.Parse(layout, strings.TrimSpace(it.PubDate)); err == nil {
+				item.Published = t
+				break
+			}
+		}
+		text := strings.ToLower(item.Title + " " + item.Description)
+		item.ManualIntervention = strings.Contains(text, "manual intervention")
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// LastUpgrade returns the time of the last "starting full system upgrade"
+// entry in a pacman.log, or the zero time if there is none.
+func LastUpgrade(log io.Reader) (time.Time, error) {
+	var last time.Time
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "starting full system upgrade") {
+			continue
+		}
+		if t, ok := parseLogTime(line); ok {
+			last = t
+		}
+	}
+	return last, scanner.Err()
+}
+
+// parseLogTime reads the leading [timestamp] of a pacman.log line, in either
+// the current ISO 8601 format or the pre-5.1 "2006-01-02 15:04" one.
+func parseLogTime(line string) (time.Time, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return time.Time{}, false
+	}
+	end := strings.Index(line, "]")
+	if end < 0 {
+		return time.Time{}, false
+	}
+	stamp := line[1:end]
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", stamp); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", stamp, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// recentNews is how many of the latest items UnreadNews returns when there
+// is no upgrade to compare against.
+const recentNews = 5
+
+// UnreadNews returns the news items published since the last full system
+// upgrade recorded in pacman.log, and when that was. When pacman.log can't be
+// read or records no upgrade, since is zero and only the latest few items
+// are returned.
+func UnreadNews(ctx context.Context) (items []NewsItem, since time.Time, err error) {
+	items, err = FetchNews(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if log, err := backend.PacmanLog(); err == nil {
+		since, _ = LastUpgrade(log)
+		log.Close()
+	}
+	if since.IsZero() {
+		return items[:min(len(items), recentNews)], since, nil
+	}
+
+	var unread []NewsItem
+	for _, it := range items {
+		if it.Published.After(since) {
+			unread = append(unread, it)
+		}
+	}
+	return unread, since, nil
+}
@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFeed = `<?xml version="1.0" encoding="utf-8"?>
+<rss version="2.0"><channel><title>Arch Linux: Recent news updates</title>
+<item><title>NVIDIA 560 drivers require manual intervention</title><link>https://archlinux.org/news/nvidia-560/</link>
+<description>&lt;p&gt;Users of the &lt;code&gt;nvidia&lt;/code&gt; package must &amp;amp; should read this.&lt;/p&gt;</description>
+<pubDate>Mon, 05 Aug 2024 10:00:00 +0000</pubDate></item>
+<item><title>Valkey to replace Redis</title><link>https://archlinux.org/news/valkey/</link>
+<description>&lt;p&gt;Redis is moving to the AUR.&lt;/p&gt;</description>
+<pubDate>Tue, 16 Apr 2024 08:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+func TestLastUpgrade(t *testing.T) {
+	log := `[2019-03-01 09:00] [PACMAN] starting full system upgrade
+[2024-07-01T12:00:00+0200] [PACMAN] Running 'pacman -Syu'
+[2024-07-01T12:00:01+0200] [PACMAN] starting full system upgrade
+[2024-07-01T12:00:30+0200] [ALPM] upgraded bash (5.2.026-1 -> 5.2.026-2)
+[2024-07-02T08:00:00+0200] [PACMAN] Running 'pacman -S vim'
+`
+	last, err := LastUpgrade(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("LastUpgrade() returned error: %v", err)
+	}
+	expected := time.Date(2024, 7, 1, 10, 0, 1, 0, time.UTC)
+	if !last.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, last)
+	}
+}
+
+func TestUnreadNews(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+	SetNewsURL(srv.URL)
+	defer SetNewsURL("")
+
+	fake := NewFakeBackend()
+	fake.Log = "[2024-07-01T12:00:01+0000] [PACMAN] starting full system upgrade\n"
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	items, since, err := UnreadNews(context.Background())
+	if err != nil {
+		t.Fatalf("UnreadNews() returned error: %v", err)
+	}
+	if len(items) != 1 || since.IsZero() {
+		t.Fatalf("Expected 1 unread item since the last upgrade, got %d since %v", len(items), since)
+	}
+	it := items[0]
+	if !it.ManualIntervention {
+		t.Errorf("Expected %q to need manual intervention", it.Title)
+	}
+	if it.Description != "Users of the nvidia package must & should read this." {
+		t.Errorf("Unexpected description %q", it.Description)
+	}
+	if it.Link != "https://archlinux.org/news/nvidia-560/" {
+		t.Errorf("Unexpected link %q", it.Link)
+	}
+}
+
+func TestUnreadNewsWithoutUpgrade(t *testing.T) {
+	var feed strings.Builder
+	feed.WriteString(`<?xml version="1.0" encoding="utf-8"?><rss version="2.0"><channel>`)
+	for i := range 8 {
+		fmt.Fprintf(&feed, "<item><title>News %d</title><pubDate>Mon, 0%d Jan 2024 10:00:00 +0000</pubDate></item>", i, 9-i)
+	}
+	feed.WriteString("</channel></rss>")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(feed.String()))
+	}))
+	defer srv.Close()
+	SetNewsURL(srv.URL)
+	defer SetNewsURL("")
+
+	// No upgrade in the log, so the date of the last one is unknown.
+	SetBackend(NewFakeBackend())
+	defer SetBackend(nil)
+
+	items, since, err := UnreadNews(context.Background())
+	if err != nil {
+		t.Fatalf("UnreadNews() returned error: %v", err)
+	}
+	if !since.IsZero() || len(items) != recentNews || items[0].Title != "News 0" {
+		t.Errorf("Expected the latest %d items with no date, got %d since %v", recentNews, len(items), since)
+	}
+}
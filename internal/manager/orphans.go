@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Orphans lists packages installed as dependencies that nothing installed
+// requires any more, like pacman -Qdt. With optional set, packages that are
+// only optionally required are included too, like pacman -Qdtt.
+func Orphans(optional bool) ([]Package, error) {
+	return backend.Orphans(optional)
+}
+
+func (b *ArchBackend) Orphans(optional bool) ([]Package, error) {
+	local, err := b.localPackages()
+	if err != nil {
+		return b.orphansFromPacman(optional)
+	}
+	return findOrphans(local, optional), nil
+}
+
+// findOrphans applies pacman's -Qdt rules to the local package set.
+func findOrphans(local []Package, optional bool) []Package {
+	required := make(map[string]bool)
+	for _, p := range local {
+		for _, dep := range p.Depends {
+			required[depName(dep)] = true
+		}
+		if !optional {
+			for _, dep := range p.OptDepends {
+				// "name: description"
+				name, _, _ := strings.Cut(dep, ":")
+				required[depName(strings.TrimSpace(name))] = true
+			}
+		}
+	}
+
+	var orphans []Package
+	for _, p := range local {
+		if !p.Dependency || required[p.Name] {
+			continue
+		}
+		provided := false
+		for _, prov := range p.Provides {
+			if required[depName(prov)] {
+				provided = true
+				break
+			}
+		}
+		if !provided {
+			p.IsInstalled = true
+			p.Repository = "local"
+			orphans = append(orphans, p)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Name < orphans[j].Name })
+	return orphans
+}
+
+func (b *ArchBackend) orphansFromPacman(optional bool) ([]Package, error) {
+	flag := "-Qdtq"
+	if optional {
+		flag = "-Qdttq"
+	}
+	cmd := exec.Command("pacman", flag)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		// pacman exits 1 when there is nothing to list.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && len(out) == 0 {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var orphans []Package
+	for _, name := range strings.Fields(string(out)) {
+		p := Package{Name: name, IsInstalled: true}
+		if err := getPacmanDetails(&p, "-Qi"); err != nil {
+			return nil, err
+		}
+		p.Repository = "local"
+		p.Detailed = true
+		orphans = append(orphans, p)
+	}
+	return orphans, nil
+}
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	local := []Package{
+		{Name: "firefox", Depends: []string{"gtk3", "libnotify>=0.7"}, OptDepends: []string{"hunspell: spell checking"}},
+		{Name: "gtk3", Dependency: true},
+		{Name: "libnotify", Dependency: true},
+		{Name: "hunspell", Dependency: true, InstalledBytes: 1024},
+		{Name: "old-lib", Dependency: true, InstalledBytes: 2048},
+		// Required through what it provides.
+		{Name: "jack2", Dependency: true, Provides: []string{"jack=2"}},
+		{Name: "ardour", Depends: []string{"jack"}},
+		// Explicitly installed packages are never orphans.
+		{Name: "htop"},
+	}
+
+	names := func(pkgs []Package) []string {
+		var out []string
+		for _, p := range pkgs {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	if got := names(findOrphans(local, false)); !slices.Equal(got, []string{"old-lib"}) {
+		t.Errorf("Expected -Qdt to find [old-lib], got %v", got)
+	}
+	orphans := findOrphans(local, true)
+	if got := names(orphans); !slices.Equal(got, []string{"hunspell", "old-lib"}) {
+		t.Errorf("Expected -Qdtt to find [hunspell old-lib], got %v", got)
+	}
+	if !orphans[0].IsInstalled || orphans[0].InstalledBytes != 1024 {
+		t.Errorf("Unexpected orphan: %+v", orphans[0])
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512.00 B":   512,
+		"1.50 KiB":   1536,
+		"48.00 MiB":  50331648,
+		"garbage":    0,
+		"1.00 Bytes": 0,
+	}
+	for in, expected := range tests {
+		if got := parseSize(in); got != expected {
+			t.Errorf("parseSize(%q) = %d, expected %d", in, got, expected)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package manager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultConfPath = "/etc/pacman.conf"
+	defaultLogPath  = "/var/log/pacman.log"
+)
+
+// maxIncludeDepth guards against Include loops.
+const maxIncludeDepth = 10
+
+// PacmanConf holds the parts of pacman.conf that gopac cares about.
+type PacmanConf struct {
+	Repositories []string
+	CacheDirs    []string
+	LogFile      string
+	DBPath       string
+}
+
+// ParsePacmanConf reads pacman.conf and every file it Includes, returning the
+// enabled repositories in priority order.
+func ParsePacmanConf(path string) (*PacmanConf, error) {
+	conf := &PacmanConf{}
+	if err := conf.parseFile(path, "", 0); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (c *PacmanConf) parseFile(path, section string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: too many nested Includes", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				return fmt.Errorf("%s:%d: invalid section %q", path, lineNo, line)
+			}
+			section = line[1 : len(line)-1]
+			if section != "options" && !slices.Contains(c.Repositories, section) {
+				c.Repositories = append(c.Repositories, section)
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		if key == "Include" {
+			matches, err := filepath.Glob(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			for _, m := range matches {
+				if err := c.parseFile(m, section, depth+1); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if section != "options" {
+			continue
+		}
+		switch key {
+		case "CacheDir":
+			// One line may list several directories.
+			c.CacheDirs = append(c.CacheDirs, strings.Fields(value)...)
+		case "LogFile":
+			c.LogFile = value
+		case "DBPath":
+			c.DBPath = value
+		}
+	}
+	return scanner.Err()
+}
+
+func (b *ArchBackend) confPath() string {
+	if b.ConfPath != "" {
+		return b.ConfPath
+	}
+	return defaultConfPath
+}
+
+// pacmanConf returns the parsed pacman.conf, reading it again only when its
+// modification time changes. Edits to Included files alone go unnoticed.
+func (b *ArchBackend) pacmanConf() (*PacmanConf, error) {
+	info, err := os.Stat(b.confPath())
+	if err != nil {
+		return nil, err
+	}
+
+	b.cache.mu.Lock()
+	defer b.cache.mu.Unlock()
+	if b.cache.conf != nil && b.cache.confMod.Equal(info.ModTime()) {
+		return b.cache.conf, nil
+	}
+
+	conf, err := ParsePacmanConf(b.confPath())
+	if err != nil {
+		return nil, err
+	}
+	b.cache.conf = conf
+	b.cache.confMod = info.ModTime()
+	return conf, nil
+}
+
+func (b *ArchBackend) logPath() string {
+	if b.LogPath != "" {
+		return b.LogPath
+	}
+	if conf, err := b.pacmanConf(); err == nil && conf.LogFile != "" {
+		return conf.LogFile
+	}
+	return defaultLogPath
+}
+
+// PacmanLog opens the pacman log named by LogPath, pacman.conf or the default.
+func (b *ArchBackend) PacmanLog() (io.ReadCloser, error) {
+	return os.Open(b.logPath())
+}
+
+// Repositories lists the sync repositories from pacman.conf, falling back to
+// whatever databases exist under the sync directory.
+func (b *ArchBackend) Repositories() ([]string, error) {
+	if conf, err := b.pacmanConf(); err == nil && len(conf.Repositories) > 0 {
+		return conf.Repositories, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(b.dbPath(), "sync", "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	var repos []string
+	for _, p := range paths {
+		repos = append(repos, strings.TrimSuffix(filepath.Base(p), ".db"))
+	}
+	slices.Sort(repos)
+	return repos, nil
+}
+
+func Repositories() ([]string, error) {
+	return backend.Repositories()
+}
@@ -0,0 +1,127 @@
+package manager
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParsePacmanConf(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mirrorlist := filepath.Join(tmpDir, "mirrorlist")
+	if err := os.WriteFile(mirrorlist, []byte("Server = https://geo.mirror.pkgbuild.com/$repo/os/$arch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reposDir := filepath.Join(tmpDir, "repos.d")
+	if err := os.MkdirAll(reposDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	extraRepos := "[chaotic-aur]\nInclude = " + mirrorlist + "\n\n[corp]\nServer = https://repo.example.com/$arch\n"
+	if err := os.WriteFile(filepath.Join(reposDir, "extra.conf"), []byte(extraRepos), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := `# /etc/pacman.conf
+[options]
+CacheDir = /var/cache/pacman/pkg/
+CacheDir = /srv/pkgcache /mnt/pkgcache
+DBPath = /srv/pacman/
+IgnorePkg = linux nvidia # held back
+IgnorePkg = mesa
+LogFile = /var/log/pacman.log
+
+#[core-testing]
+#Include = ` + mirrorlist + `
+
+[core]
+Include = ` + mirrorlist + `
+
+[extra]
+Include = ` + mirrorlist + `
+
+Include = ` + filepath.Join(reposDir, "*.conf") + `
+`
+	confPath := filepath.Join(tmpDir, "pacman.conf")
+	if err := os.WriteFile(confPath, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParsePacmanConf(confPath)
+	if err != nil {
+		t.Fatalf("ParsePacmanConf() returned error: %v", err)
+	}
+
+	if expected := []string{"core", "extra", "chaotic-aur", "corp"}; !slices.Equal(parsed.Repositories, expected) {
+		t.Errorf("Expected repositories %v, got %v", expected, parsed.Repositories)
+	}
+	if expected := []string{"/var/cache/pacman/pkg/", "/srv/pkgcache", "/mnt/pkgcache"}; !slices.Equal(parsed.CacheDirs, expected) {
+		t.Errorf("Expected cache dirs %v, got %v", expected, parsed.CacheDirs)
+	}
+	if parsed.LogFile != "/var/log/pacman.log" {
+		t.Errorf("Expected LogFile /var/log/pacman.log, got %q", parsed.LogFile)
+	}
+	if parsed.DBPath != "/srv/pacman/" {
+		t.Errorf("Expected DBPath /srv/pacman/, got %q", parsed.DBPath)
+	}
+}
+
+func TestDBPathFromPacmanConf(t *testing.T) {
+	dbPath := newTestDB(t)
+	confPath := filepath.Join(t.TempDir(), "pacman.conf")
+	if err := os.WriteFile(confPath, []byte("[options]\nDBPath = "+dbPath+"\n[core]\n[extra]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := &ArchBackend{ConfPath: confPath}
+	if got := b.dbPath(); got != dbPath {
+		t.Fatalf("Expected the DBPath from pacman.conf, got %q", got)
+	}
+	if pkgs, err := b.localPackages(); err != nil || len(pkgs) == 0 {
+		t.Errorf("Expected the local database under DBPath, got %d packages (%v)", len(pkgs), err)
+	}
+
+	b.DBPath = "/nonexistent"
+	if got := b.dbPath(); got != "/nonexistent" {
+		t.Errorf("Expected the DBPath field to win, got %q", got)
+	}
+}
+
+func TestRepositoriesCached(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "pacman.conf")
+	if err := os.WriteFile(confPath, []byte("[core]\n[extra]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := &ArchBackend{ConfPath: confPath}
+	first, err := b.Repositories()
+	if err != nil || !slices.Equal(first, []string{"core", "extra"}) {
+		t.Fatalf("Expected core and extra, got %v (%v)", first, err)
+	}
+	again, _ := b.Repositories()
+	if &again[0] != &first[0] {
+		t.Error("Expected an unchanged pacman.conf to be parsed only once")
+	}
+
+	if err := os.WriteFile(confPath, []byte("[core]\n[multilib]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(confPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if repos, _ := b.Repositories(); !slices.Equal(repos, []string{"core", "multilib"}) {
+		t.Errorf("Expected the edited repositories, got %v", repos)
+	}
+}
+
+func TestParsePacmanConfIncludeLoop(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "pacman.conf")
+	if err := os.WriteFile(confPath, []byte("[core]\nInclude = "+confPath+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParsePacmanConf(confPath); err == nil {
+		t.Error("Expected an error for a recursive Include")
+	}
+}
@@ -0,0 +1,340 @@
+package manager
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PackageQuery is a search term plus filters over the results, e.g.
+// "editor repo:extra installed:no size:<20M updated:<1y license:GPL".
+type PackageQuery struct {
+	// Text goes to SearchContext, field prefixes such as desc: included.
+	Text    string
+	Filters []PackageFilter
+	// Descriptions searches descriptions as well as names when Text has no
+	// field prefix, as if it started with desc:.
+	Descriptions bool
+}
+
+// PackageFilter is one key:value term of a PackageQuery.
+type PackageFilter struct {
+	Key   string
+	Value string
+	match func(p Package) bool
+}
+
+// QueryFilterKeys are the keys ParsePackageQuery treats as filters. Foreign
+// packages count as AUR ones for repo: and aur:.
+var QueryFilterKeys = []string{"repo", "installed", "aur", "outdated", "votes", "popularity", "size", "updated", "license"}
+
+// ParsePackageQuery splits the filters out of a search. Terms whose key
+// isn't a filter, like desc:editor or file:/usr/bin/vim, stay in Text.
+func ParsePackageQuery(query string) (PackageQuery, error) {
+	var q PackageQuery
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		key, value, ok := strings.Cut(field, ":")
+		key = strings.ToLower(key)
+		if !ok || isSearchPrefix(key) {
+			terms = append(terms, field)
+			continue
+		}
+		if !slices.Contains(QueryFilterKeys, key) {
+			// Package names can't contain colons, so this is a typo.
+			return q, fmt.Errorf("unknown filter %q (want %s)", key, strings.Join(QueryFilterKeys, ", "))
+		}
+		if value == "" {
+			return q, fmt.Errorf("%s: missing value", key)
+		}
+		match, err := parseFilter(key, value)
+		if err != nil {
+			return q, err
+		}
+		q.Filters = append(q.Filters, PackageFilter{Key: key, Value: value, match: match})
+	}
+	q.Text = strings.Join(terms, " ")
+	return q, nil
+}
+
+func isSearchPrefix(key string) bool {
+	if key == "file" {
+		return true
+	}
+	for _, sp := range SearchPrefixes {
+		if sp.Prefix == key+":" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFilter(key, value string) (func(Package) bool, error) {
+	switch key {
+	case "repo":
+		repos := strings.Split(strings.ToLower(value), ",")
+		return func(p Package) bool {
+			repo := strings.ToLower(p.Repository)
+			if p.IsAUR || p.Foreign {
+				repo = "aur"
+			}
+			return slices.Contains(repos, repo)
+		}, nil
+
+	case "installed", "aur", "outdated":
+		want, err := parseYesNo(key, value)
+		if err != nil {
+			return nil, err
+		}
+		return func(p Package) bool {
+			switch key {
+			case "installed":
+				return p.IsInstalled == want
+			case "aur":
+				return (p.IsAUR || p.Foreign) == want
+			default:
+				outdated := p.InstalledVersion != "" && Vercmp(p.Version, p.InstalledVersion) > 0
+				return outdated == want
+			}
+		}, nil
+
+	case "votes", "popularity":
+		op, num := splitOp(value)
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a number like >50, got %q", key, value)
+		}
+		return func(p Package) bool {
+			v := p.Popularity
+			if key == "votes" {
+				v = float64(p.Votes)
+			}
+			return compareOp(op, cmp.Compare(v, n))
+		}, nil
+
+	case "size":
+		op, num := splitOp(value)
+		n, err := parseSizeArg(num)
+		if err != nil {
+			return nil, fmt.Errorf("size: expected a size like <20M, got %q", value)
+		}
+		if op == "=" {
+			// Sizes are rounded; an exact byte count would hardly ever match.
+			op = ">="
+		}
+		return func(p Package) bool {
+			return p.InstalledBytes > 0 && compareOp(op, cmp.Compare(p.InstalledBytes, n))
+		}, nil
+
+	case "updated":
+		op, arg := splitOp(value)
+		t, age, err := parseWhen(arg)
+		if err != nil {
+			return nil, fmt.Errorf("updated: expected an age like <1y or a date like >2024-01-31, got %q", value)
+		}
+		switch {
+		case age && op == "=":
+			// A bare age, as in updated:30d, means within it.
+			op = ">"
+		case age:
+			// Younger than an age means later than the time it points at.
+			op = flipOp(op)
+		}
+		return func(p Package) bool {
+			updated := p.LastModified
+			if !p.IsAUR {
+				updated = p.BuildDate
+			}
+			if updated <= 0 {
+				// Unknown, e.g. from the pacman -Ss fallback; keep the
+				// package rather than guess.
+				return true
+			}
+			if op == "=" {
+				// A bare date is the whole day.
+				return updated >= t.Unix() && updated < t.AddDate(0, 0, 1).Unix()
+			}
+			return compareOp(op, cmp.Compare(updated, t.Unix()))
+		}, nil
+
+	case "license":
+		return func(p Package) bool {
+			return slices.ContainsFunc(p.Licenses, func(l string) bool {
+				return strings.Contains(strings.ToLower(l), strings.ToLower(value))
+			})
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown filter %q", key)
+}
+
+func parseYesNo(key, value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "true":
+		return true, nil
+	case "no", "n", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%s: expected yes or no, got %q", key, value)
+}
+
+// splitOp splits a comparison operator off a value; a bare value is "=".
+func splitOp(value string) (op, rest string) {
+	for _, o := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, o); ok {
+			return o, rest
+		}
+	}
+	return "=", value
+}
+
+func compareOp(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	default:
+		return c == 0
+	}
+}
+
+func flipOp(op string) string {
+	switch op {
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	}
+	return op
+}
+
+// parseSizeArg reads sizes like 512K, 20M, 1.5G or 300 (bytes), in powers of
+// 1024 like pacman.
+func parseSizeArg(s string) (int64, error) {
+	num := strings.TrimRight(strings.ToUpper(s), "IB")
+	mult := 1.0
+	if num != "" {
+		if i := strings.IndexByte("KMGT", num[len(num)-1]); i >= 0 {
+			num = num[:len(num)-1]
+			for range i + 1 {
+				mult *= 1024
+			}
+		}
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(val * mult), nil
+}
+
+// parseWhen reads a date (2024-01-31) or an age (3d, 2w, 6m, 1y) and
+// returns the time it stands for; age reports which it was.
+func parseWhen(s string) (t time.Time, age bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, false, nil
+	}
+	if len(s) < 2 {
+		return time.Time{}, false, fmt.Errorf("invalid age %q", s)
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false, fmt.Errorf("invalid age %q", s)
+	}
+	now := time.Now()
+	switch s[len(s)-1] {
+	case 'h':
+		return now.Add(-time.Duration(n) * time.Hour), true, nil
+	case 'd':
+		return now.AddDate(0, 0, -n), true, nil
+	case 'w':
+		return now.AddDate(0, 0, -7*n), true, nil
+	case 'm':
+		return now.AddDate(0, -n, 0), true, nil
+	case 'y':
+		return now.AddDate(-n, 0, 0), true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid age %q", s)
+}
+
+// Matches reports whether p passes every filter. Text isn't checked; it is
+// what the search matched on.
+func (q PackageQuery) Matches(p Package) bool {
+	for _, f := range q.Filters {
+		if !f.match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q PackageQuery) filters(key string) bool {
+	return slices.ContainsFunc(q.Filters, func(f PackageFilter) bool { return f.Key == key })
+}
+
+// Search runs Text through SearchContext and filters the results. Filters
+// without a search term apply to every package in the sync databases.
+func (q PackageQuery) Search(ctx context.Context) ([]Package, error) {
+	if q.Text == "" && len(q.Filters) == 0 {
+		return nil, nil
+	}
+	return searchAll(ctx, q.searchText(), q.SearchIn)
+}
+
+// SearchIn is Search for a single source. The results are not ranked.
+// AUR search results carry no licenses, so they are looked up when filtered
+// on. The AUR can't be listed whole, so it is only searched with a term.
+func (q PackageQuery) SearchIn(ctx context.Context, source SearchSource) ([]Package, error) {
+	var pkgs []Package
+	var err error
+	switch {
+	case q.Text != "":
+		pkgs, err = SearchSourceContext(ctx, source, q.searchText())
+	case len(q.Filters) > 0 && source == SourceRepos:
+		// An empty name search matches every package.
+		pkgs, err = backend.Search(ctx, source, "", ByName)
+		if len(pkgs) > 0 {
+			checkInstalledStatus(pkgs)
+		}
+	}
+	if err != nil || len(q.Filters) == 0 {
+		return pkgs, err
+	}
+
+	if q.filters("license") && source == SourceAUR && len(pkgs) > 0 {
+		names := make([]string, len(pkgs))
+		for i, p := range pkgs {
+			names[i] = p.Name
+		}
+		info, err := aurInfo(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		for i := range pkgs {
+			pkgs[i].Licenses = info[pkgs[i].Name].Licenses
+		}
+	}
+
+	return slices.DeleteFunc(pkgs, func(p Package) bool { return !q.Matches(p) }), nil
+}
+
+// searchText is Text as sent to the sources, with desc: added when
+// Descriptions asks for it.
+func (q PackageQuery) searchText() string {
+	if by, _ := ParseSearchQuery(q.Text); q.Descriptions && by == ByName && !strings.HasPrefix(q.Text, "file:") {
+		return "desc:" + q.Text
+	}
+	return q.Text
+}
@@ -0,0 +1,202 @@
+package manager
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePackageQuery(t *testing.T) {
+	q, err := ParsePackageQuery("text editor repo:extra,core installed:no votes:>50 size:<20M updated:<1y license:GPL")
+	if err != nil {
+		t.Fatalf("ParsePackageQuery: %v", err)
+	}
+	if q.Text != "text editor" || len(q.Filters) != 6 {
+		t.Errorf("Expected the text and 6 filters, got %q and %+v", q.Text, q.Filters)
+	}
+
+	q, err = ParsePackageQuery("desc:editor  file:/usr/bin/vim")
+	if err != nil || q.Text != "desc:editor file:/usr/bin/vim" || len(q.Filters) != 0 {
+		t.Errorf("Expected search prefixes to stay in the text, got %+v (%v)", q, err)
+	}
+
+	errs := map[string]string{
+		"vim rpo:extra":       `unknown filter "rpo"`,
+		"vim installed:":      "installed: missing value",
+		"vim installed:maybe": `installed: expected yes or no, got "maybe"`,
+		"vim votes:>lots":     `votes: expected a number like >50, got ">lots"`,
+		"vim size:<20Q":       `size: expected a size like <20M, got "<20Q"`,
+		"vim updated:<1x":     `updated: expected an age like <1y or a date like >2024-01-31, got "<1x"`,
+	}
+	for query, want := range errs {
+		if _, err := ParsePackageQuery(query); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("ParsePackageQuery(%q) error = %v; expected %q", query, err, want)
+		}
+	}
+}
+
+func TestPackageQueryMatches(t *testing.T) {
+	now := time.Now()
+	vim := Package{
+		Name: "vim", Repository: "extra", Version: "9.1-2", IsInstalled: true, InstalledVersion: "9.1-1",
+		InstalledBytes: 4 << 20, BuildDate: now.AddDate(0, -2, 0).Unix(), Licenses: []string{"custom:vim"},
+	}
+	yay := Package{
+		Name: "yay", IsAUR: true, Version: "12.3.5-1", Votes: 2300, Popularity: 21.5,
+		LastModified: now.AddDate(-2, 0, 0).Unix(),
+	}
+	emacs := Package{
+		Name: "emacs", Repository: "extra", Version: "29.4-1", InstalledBytes: 150 << 20,
+		BuildDate: time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local).Unix(), Licenses: []string{"GPL-3.0-or-later"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"repo:extra", []string{"vim", "emacs"}},
+		{"repo:aur,core", []string{"yay"}},
+		{"installed:yes", []string{"vim"}},
+		{"aur:no installed:no", []string{"emacs"}},
+		{"outdated:yes", []string{"vim"}},
+		{"votes:>=2300", []string{"yay"}},
+		{"popularity:<1", []string{"vim", "emacs"}},
+		{"size:<20M", []string{"vim"}},
+		{"size:>1G", nil},
+		{"size:100M", []string{"emacs"}},
+		{"size:=4M", []string{"vim", "emacs"}},
+		{"updated:<1y", []string{"vim"}},
+		{"updated:>1y", []string{"yay", "emacs"}},
+		{"updated:<2024-07-01 repo:extra", []string{"emacs"}},
+		{"updated:2024-06-01", []string{"emacs"}},
+		{"updated:2024-05-31", nil},
+		{"updated:3m", []string{"vim"}},
+		{"license:gpl", []string{"emacs"}},
+	}
+	for _, tt := range tests {
+		q, err := ParsePackageQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParsePackageQuery(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, p := range []Package{vim, yay, emacs} {
+			if q.Matches(p) {
+				got = append(got, p.Name)
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q matched %v; expected %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPackageQuerySearch(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "vim", Repository: "extra", Description: "Vi Improved", InstalledBytes: 4 << 20},
+		Package{Name: "gvim", Repository: "extra", Description: "Vi Improved, with a GUI", InstalledBytes: 9 << 20},
+		Package{Name: "vim-plug", IsAUR: true, Description: "Vim plugin manager", Votes: 40},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+	ctx := context.Background()
+
+	q, _ := ParsePackageQuery("vim repo:extra size:<5M")
+	pkgs, err := q.Search(ctx)
+	if err != nil || len(pkgs) != 1 || pkgs[0].Name != "vim" {
+		t.Errorf("Expected only vim, got %+v (%v)", pkgs, err)
+	}
+
+	q, _ = ParsePackageQuery("desc:improved aur:no")
+	if pkgs, err := q.Search(ctx); err != nil || len(pkgs) != 2 {
+		t.Errorf("Expected vim and gvim by description, got %+v (%v)", pkgs, err)
+	}
+
+}
+
+func TestPackageQueryFiltersOnly(t *testing.T) {
+	recent := time.Now().AddDate(0, -1, 0).Unix()
+	fake := NewFakeBackend(
+		Package{Name: "nano", Repository: "core", InstalledBytes: 2 << 20, BuildDate: recent, Licenses: []string{"GPL-3.0-or-later"}},
+		Package{Name: "vim", Repository: "extra", InstalledBytes: 4 << 20, BuildDate: recent, Licenses: []string{"custom:vim"}},
+		Package{Name: "gedit", Repository: "extra", InstalledBytes: 15 << 20, BuildDate: recent, Licenses: []string{"GPL-2.0-or-later"}},
+		Package{Name: "emacs", Repository: "extra", InstalledBytes: 150 << 20, BuildDate: recent, Licenses: []string{"GPL-3.0-or-later"}},
+		Package{Name: "kate", Repository: "extra", IsInstalled: true, InstalledBytes: 10 << 20, BuildDate: recent, Licenses: []string{"LGPL-2.0-or-later"}},
+		Package{Name: "micro-editor-bin", IsAUR: true, Votes: 80, Licenses: []string{"MIT"}},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+	ctx := context.Background()
+
+	// Repository packages have no votes, so the full example from the
+	// request only narrows down to nothing; it mustn't be refused though.
+	q, _ := ParsePackageQuery("repo:extra installed:no votes:>50 size:<20M updated:<1y license:GPL")
+	if pkgs, err := q.Search(ctx); err != nil || len(pkgs) != 0 {
+		t.Errorf("Expected no error and no results, got %+v (%v)", pkgs, err)
+	}
+
+	q, _ = ParsePackageQuery("repo:extra installed:no size:<20M updated:<1y license:GPL")
+	pkgs, err := q.Search(ctx)
+	if err != nil || len(pkgs) != 1 || pkgs[0].Name != "gedit" {
+		t.Errorf("Expected only gedit from the sync databases, got %+v (%v)", pkgs, err)
+	}
+
+	// The AUR is only searched with a term.
+	q, _ = ParsePackageQuery("votes:>50")
+	if pkgs, err := q.Search(ctx); err != nil || len(pkgs) != 0 {
+		t.Errorf("Expected no AUR results without a term, got %+v (%v)", pkgs, err)
+	}
+	q, _ = ParsePackageQuery("micro votes:>50")
+	if pkgs, err := q.Search(ctx); err != nil || len(pkgs) != 1 {
+		t.Errorf("Expected micro-editor-bin, got %+v (%v)", pkgs, err)
+	}
+}
+
+func TestPackageQueryUnknownDate(t *testing.T) {
+	// pacman -Ss output carries no build date.
+	p := Package{Name: "vim", Repository: "extra"}
+	for _, query := range []string{"updated:<1y", "updated:>1y", "updated:2024-06-01"} {
+		q, err := ParsePackageQuery(query)
+		if err != nil {
+			t.Fatalf("ParsePackageQuery(%q): %v", query, err)
+		}
+		if !q.Matches(p) {
+			t.Errorf("Expected %q to keep a package without a date", query)
+		}
+	}
+}
+
+func TestPackageQuerySearchDescriptions(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "nano", Repository: "core", Description: "Pico editor clone with enhancements"},
+		Package{Name: "vim", Repository: "extra", Description: "Vi Improved, a highly configurable text editor"},
+		Package{Name: "editorconfig-core-c", Repository: "extra", Description: "EditorConfig core code written in C"},
+		Package{Name: "micro-editor-bin", IsAUR: true, Votes: 30, Description: "A modern terminal-based text editor"},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	q, _ := ParsePackageQuery("editor")
+	pkgs, err := q.Search(context.Background())
+	if err != nil || len(pkgs) != 2 {
+		t.Fatalf("Expected only name matches, got %+v (%v)", pkgs, err)
+	}
+
+	q.Descriptions = true
+	pkgs, err = q.Search(context.Background())
+	if err != nil || len(pkgs) != 4 {
+		t.Fatalf("Expected name and description matches, got %+v (%v)", pkgs, err)
+	}
+	for i, want := range []string{"editorconfig-core-c", "micro-editor-bin"} {
+		if pkgs[i].Name != want {
+			t.Errorf("Expected name matches before description matches, got %s at %d", pkgs[i].Name, i)
+		}
+	}
+
+	// Explicit fields win over the toggle.
+	q, _ = ParsePackageQuery("maint:nobody")
+	q.Descriptions = true
+	if pkgs, err := q.Search(context.Background()); err != nil || len(pkgs) != 0 {
+		t.Errorf("Expected a maintainer search, got %+v (%v)", pkgs, err)
+	}
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sahilm/fuzzy"
+)
+
+// A Scorer rates how well a search result matches the query. SearchContext
+// lists higher scores first.
+type Scorer interface {
+	Score(p Package, query string) float64
+}
+
+// RankWeights is the default Scorer: a weighted sum of signals that are each
+// between 0 and 1.
+type RankWeights struct {
+	Exact        float64 // the name is the query
+	Fuzzy        float64 // how closely the name matches, see FuzzyQuality
+	Prefix       float64 // the name starts with the query
+	WordBoundary float64 // the query starts a word in the name, as in python-[requests]
+	Popularity   float64 // AUR votes, on a log scale up to 1000
+	Installed    float64
+	Official     float64 // the package is from a repository, not the AUR
+}
+
+// DefaultRankWeights keep exact matches on top and official packages above
+// similar AUR ones.
+var DefaultRankWeights = RankWeights{
+	Exact:        100,
+	Fuzzy:        20,
+	Prefix:       10,
+	WordBoundary: 5,
+	Popularity:   8,
+	Installed:    5,
+	Official:     15,
+}
+
+var scorer Scorer = DefaultRankWeights
+
+// SetScorer replaces how search results are ranked; nil restores the
+// default weights.
+func SetScorer(s Scorer) {
+	if s == nil {
+		s = DefaultRankWeights
+	}
+	scorer = s
+}
+
+// RankWeightsFrom overrides the default weights by name, as in the ranking
+// section of config.yaml (word_boundary for WordBoundary).
+func RankWeightsFrom(overrides map[string]float64) (RankWeights, error) {
+	w := DefaultRankWeights
+	fields := map[string]*float64{
+		"exact":         &w.Exact,
+		"fuzzy":         &w.Fuzzy,
+		"prefix":        &w.Prefix,
+		"word_boundary": &w.WordBoundary,
+		"popularity":    &w.Popularity,
+		"installed":     &w.Installed,
+		"official":      &w.Official,
+	}
+	var unknown []string
+	for name, value := range overrides {
+		field, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		*field = value
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return w, fmt.Errorf("unknown ranking weights: %s", strings.Join(unknown, ", "))
+	}
+	return w, nil
+}
+
+func (w RankWeights) Score(p Package, query string) float64 {
+	name, query := strings.ToLower(p.Name), strings.ToLower(query)
+	signal := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	score := w.Fuzzy * FuzzyQuality(p.Name, query)
+	if query != "" {
+		idx := strings.Index(name, query)
+		score += w.Exact * signal(name == query)
+		score += w.Prefix * signal(idx == 0)
+		score += w.WordBoundary * signal(idx > 0 && strings.ContainsRune("-_.", rune(name[idx-1])))
+	}
+	if p.IsAUR {
+		score += w.Popularity * min(1, math.Log10(1+float64(p.Votes))/3)
+	}
+	score += w.Installed * signal(p.IsInstalled)
+	score += w.Official * signal(!p.IsAUR)
+	return score
+}
+
+// FuzzyQuality is how well name matches query as a fuzzy pattern, from 0
+// (no match) to 1 (as good as the query matching itself).
+func FuzzyQuality(name, query string) float64 {
+	if query == "" {
+		return 0
+	}
+	best := fuzzy.Find(query, []string{query})
+	matches := fuzzy.Find(query, []string{name})
+	if len(best) == 0 || len(matches) == 0 || best[0].Score <= 0 {
+		return 0
+	}
+	return max(0, min(1, float64(matches[0].Score)/float64(best[0].Score)))
+}
+
+// MatchPositions returns the rune positions of name to highlight for query:
+// the first substring match, or else the fuzzy-matched characters.
+func MatchPositions(name, query string) []int {
+	if query == "" {
+		return nil
+	}
+	nameRunes := []rune(strings.ToLower(name))
+	queryRunes := []rune(strings.ToLower(query))
+	for start := 0; start+len(queryRunes) <= len(nameRunes); start++ {
+		if slices.Equal(nameRunes[start:start+len(queryRunes)], queryRunes) {
+			positions := make([]int, len(queryRunes))
+			for i := range positions {
+				positions[i] = start + i
+			}
+			return positions
+		}
+	}
+
+	matches := fuzzy.Find(query, []string{name})
+	if len(matches) == 0 {
+		return nil
+	}
+	positions := make([]int, len(matches[0].MatchedIndexes))
+	for i, byteIdx := range matches[0].MatchedIndexes {
+		positions[i] = utf8.RuneCountInString(name[:byteIdx])
+	}
+	return positions
+}
+
+// rankPackages orders search results by score, then shorter names first.
+// Packages whose name contains the query come before those that only matched
+// on their description, whatever their score.
+func rankPackages(pkgs []Package, query string) {
+	type ranked struct {
+		p         Package
+		nameMatch bool
+		score     float64
+	}
+	lowQuery := strings.ToLower(query)
+	list := make([]ranked, len(pkgs))
+	for i, p := range pkgs {
+		list[i] = ranked{p, strings.Contains(strings.ToLower(p.Name), lowQuery), scorer.Score(p, query)}
+	}
+	slices.SortStableFunc(list, func(a, b ranked) int {
+		if a.nameMatch != b.nameMatch {
+			if a.nameMatch {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			cmp.Compare(len(a.p.Name), len(b.p.Name)),
+			strings.Compare(a.p.Name, b.p.Name),
+		)
+	})
+	for i, r := range list {
+		pkgs[i] = r.p
+	}
+}
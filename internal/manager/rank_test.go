@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRankPackages(t *testing.T) {
+	pkgs := []Package{
+		{Name: "neovim-qt", Repository: "extra"},
+		{Name: "python-neovim", Repository: "extra"},
+		{Name: "neovim-git", IsAUR: true, Votes: 900},
+		{Name: "nvim-packer-git", IsAUR: true, Votes: 50},
+		{Name: "neovim", Repository: "extra"},
+		{Name: "neovim-nightly", IsAUR: true, Votes: 2},
+	}
+	names := func() []string {
+		var out []string
+		for _, p := range pkgs {
+			out = append(out, p.Name)
+		}
+		return out
+	}
+
+	rankPackages(pkgs, "neovim")
+	want := []string{"neovim", "neovim-qt", "python-neovim", "neovim-git", "neovim-nightly", "nvim-packer-git"}
+	if got := names(); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Without the official bonus, popular AUR packages move up.
+	weights, err := RankWeightsFrom(map[string]float64{"official": 0, "popularity": 30})
+	if err != nil {
+		t.Fatalf("RankWeightsFrom: %v", err)
+	}
+	SetScorer(weights)
+	defer SetScorer(nil)
+	rankPackages(pkgs, "neovim")
+	if got := names(); got[1] != "neovim-git" {
+		t.Errorf("Expected neovim-git second, got %v", got)
+	}
+
+	if _, err := RankWeightsFrom(map[string]float64{"votes": 1, "fuzzy": 2}); err == nil || err.Error() != "unknown ranking weights: votes" {
+		t.Errorf("Expected an unknown weight error, got %v", err)
+	}
+}
+
+func TestMatchPositions(t *testing.T) {
+	tests := []struct {
+		name, query string
+		want        []int
+	}{
+		{"python-neovim", "neovim", []int{7, 8, 9, 10, 11, 12}},
+		{"Neovim", "NEO", []int{0, 1, 2}},
+		{"nvim-packer-git", "npg", []int{0, 5, 12}},
+		{"überzug", "zug", []int{4, 5, 6}},
+		{"überzugpp", "ürp", []int{0, 3, 7}},
+		{"vim", "emacs", nil},
+	}
+	for _, tt := range tests {
+		if got := MatchPositions(tt.name, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("MatchPositions(%q, %q) = %v; expected %v", tt.name, tt.query, got, tt.want)
+		}
+	}
+
+	if q := FuzzyQuality("neovim", "neovim"); q != 1 {
+		t.Errorf("Expected an exact match to have quality 1, got %v", q)
+	}
+	if a, b := FuzzyQuality("vim-plug", "vim"), FuzzyQuality("vidmerge", "vim"); a <= b {
+		t.Errorf("Expected a tighter fuzzy match to score higher, got %v <= %v", a, b)
+	}
+}
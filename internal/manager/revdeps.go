@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ReverseDependencyTree walks RequiredBy from an installed package up to the
+// explicitly installed packages that pull it in, like pactree -r. Explicit
+// packages end their branch; Seen and Cycle are marked as in DependencyTree.
+func ReverseDependencyTree(name string) (*DepNode, error) {
+	installed := InstalledPackages()
+	byName := make(map[string]Package, len(installed))
+	for _, p := range installed {
+		byName[p.Name] = p
+	}
+	target, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not installed", name)
+	}
+
+	root := &DepNode{Dep: name, Package: target, Status: DepInstalled}
+	expanded := map[string]bool{name: true}
+	var walk func(n *DepNode, ancestors []string, depth int)
+	walk = func(n *DepNode, ancestors []string, depth int) {
+		if (!n.Package.Dependency && n != root) || depth >= maxDepTreeDepth {
+			return
+		}
+		for _, parent := range requiredBy(n.Package, installed) {
+			child := &DepNode{Dep: parent, Package: byName[parent], Status: DepInstalled}
+			n.Children = append(n.Children, child)
+			switch {
+			case slices.Contains(ancestors, parent):
+				child.Cycle = true
+			case expanded[parent]:
+				child.Seen = true
+			default:
+				expanded[parent] = true
+				walk(child, append(slices.Clone(ancestors), parent), depth+1)
+			}
+		}
+	}
+	walk(root, []string{name}, 0)
+	return root, nil
+}
+
+// maxWhyChains keeps the explanation readable for libraries that half the
+// system depends on.
+const maxWhyChains = 20
+
+// WhyInstalled lists the chains from explicitly installed packages down to
+// the root of a reverse tree, e.g. [firefox gtk3 glib2] for glib2, shortest
+// first. An explicitly installed root is its own reason.
+func WhyInstalled(root *DepNode) [][]string {
+	// Seen nodes continue where their package was expanded.
+	full := make(map[string]*DepNode)
+	var index func(n *DepNode)
+	index = func(n *DepNode) {
+		if !n.Seen && !n.Cycle {
+			full[n.Name()] = n
+		}
+		for _, c := range n.Children {
+			index(c)
+		}
+	}
+	index(root)
+
+	var chains [][]string
+	var walk func(n *DepNode, path []string)
+	walk = func(n *DepNode, path []string) {
+		if len(chains) >= maxWhyChains || slices.Contains(path, n.Name()) {
+			return
+		}
+		path = append([]string{n.Name()}, path...)
+		if !n.Package.Dependency {
+			chains = append(chains, path)
+			return
+		}
+		if n.Seen {
+			n = full[n.Name()]
+		}
+		for _, c := range n.Children {
+			walk(c, path)
+		}
+	}
+	walk(root, nil)
+
+	slices.SortStableFunc(chains, func(a, b []string) int { return len(a) - len(b) })
+	return chains
+}
@@ -0,0 +1,48 @@
+package manager
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReverseDependencyTree(t *testing.T) {
+	fake := NewFakeBackend(
+		Package{Name: "firefox", Repository: "extra", IsInstalled: true, Depends: []string{"gtk3", "glib2"}},
+		Package{Name: "gtk3", Repository: "extra", IsInstalled: true, Dependency: true, Depends: []string{"glib2"}},
+		Package{Name: "glib2", Repository: "core", IsInstalled: true, Dependency: true, Depends: []string{"libffi"}},
+		Package{Name: "libffi", Repository: "core", IsInstalled: true, Dependency: true, Provides: []string{"libffi.so=8-64"}},
+		Package{Name: "python", Repository: "core", IsInstalled: true, Depends: []string{"libffi.so=8-64"}},
+		Package{Name: "vim", Repository: "extra"},
+	)
+	SetBackend(fake)
+	defer SetBackend(nil)
+
+	root, err := ReverseDependencyTree("libffi")
+	if err != nil {
+		t.Fatalf("ReverseDependencyTree() returned error: %v", err)
+	}
+	var names []string
+	for _, c := range root.Children {
+		names = append(names, c.Name())
+	}
+	if !slices.Equal(names, []string{"glib2", "python"}) {
+		t.Fatalf("Expected glib2 and python to require libffi, got %v", names)
+	}
+	if python := root.Children[1]; python.Children != nil {
+		t.Errorf("Expected the explicitly installed python to end its branch, got %+v", python.Children)
+	}
+
+	chains := WhyInstalled(root)
+	expected := [][]string{
+		{"python", "libffi"},
+		{"firefox", "glib2", "libffi"},
+		{"firefox", "gtk3", "glib2", "libffi"},
+	}
+	if !slices.EqualFunc(chains, expected, slices.Equal) {
+		t.Errorf("Expected chains %v, got %v", expected, chains)
+	}
+
+	if _, err := ReverseDependencyTree("vim"); err == nil {
+		t.Error("Expected an error for a package that isn't installed")
+	}
+}
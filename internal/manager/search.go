@@ -2,26 +2,30 @@ package manager
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
-	"sort"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 type Package struct {
-	Name        string
-	Version     string
-	Description string
-	IsAUR       bool
-	IsInstalled bool
-	Votes       int
+	Name             string
+	Version          string
+	InstalledVersion string
+	Description      string
+	Repository       string
+	IsAUR            bool
+	IsInstalled      bool
+	Votes            int
 
 	URL          string
 	Maintainer   string
@@ -41,9 +45,12 @@ type Package struct {
 	BuildDate      int64
 	InstallDate    int64
 	InstallReason  string
+	Dependency     bool // installed as a dependency rather than explicitly
+	Foreign        bool // installed but in no sync repository, e.g. from the AUR
 	ValidatedBy    string
 	DownloadSize   string
 	InstalledSize  string
+	InstalledBytes int64
 	Popularity     float64
 	FirstSubmitted int64
 	Keywords       []string
@@ -52,71 +59,154 @@ type Package struct {
 	PKGBUILD       string
 }
 
+// QualifiedName returns repo/name for sync packages so installs resolve to the
+// repository the package was found in, even when another repo shadows it.
+func (p Package) QualifiedName() string {
+	if p.IsAUR || p.Repository == "" || p.Repository == "local" {
+		return p.Name
+	}
+	return p.Repository + "/" + p.Name
+}
+
 var httpClient = &http.Client{
 	Timeout: 15 * time.Second,
 }
 
-func SearchContext(ctx context.Context, query string) ([]Package, error) {
-	var results []Package
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+// aurBaseURL is a variable so tests can point it at a local server.
+var aurBaseURL = "https://aur.archlinux.org"
 
-	// Search Local Repos (pacman)
-	wg.Go(func() {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
+// SearchSource is one place search results come from. Sources are searched
+// separately so a slow AUR doesn't hold up the local databases.
+type SearchSource string
 
-		cmd := exec.CommandContext(ctx, "pacman", "-Ss", "--", query)
-		cmd.Env = append(os.Environ(), "LC_ALL=C")
-		out, err := cmd.Output()
-		if err != nil && ctx.Err() != nil {
-			return
-		}
-		localPkgs := parsePacmanOutput(string(out), query)
+const (
+	// SourceRepos searches the sync databases, and for file: the installed
+	// packages too.
+	SourceRepos SearchSource = "repos"
+	SourceAUR   SearchSource = "aur"
+)
+
+// SearchSources lists every source, in the order results are shown.
+var SearchSources = []SearchSource{SourceRepos, SourceAUR}
 
-		mu.Lock()
-		results = append(results, localPkgs...)
-		mu.Unlock()
+// SearchContext searches every source at once, by name unless the query
+// starts with one of the SearchPrefixes. A "file:" prefix looks up the
+// packages owning a path instead. Results from the sources that answered are
+// returned; it only fails when all of them do.
+func SearchContext(ctx context.Context, query string) ([]Package, error) {
+	return searchAll(ctx, query, func(ctx context.Context, source SearchSource) ([]Package, error) {
+		return SearchSourceContext(ctx, source, query)
 	})
+}
 
-	// Search AUR (RPC API)
-	wg.Go(func() {
-		select {
-		case <-ctx.Done():
-			return
-		default:
+// SearchSourceContext is SearchContext for a single source.
+func SearchSourceContext(ctx context.Context, source SearchSource, query string) ([]Package, error) {
+	var search func(ctx context.Context, query string) ([]Package, error)
+	if path, ok := strings.CutPrefix(query, "file:"); ok {
+		if source != SourceRepos {
+			// The files databases only cover the repositories.
+			return nil, nil
 		}
-
-		aurPkgs, err := searchAURContext(ctx, query)
-		if err == nil {
-			mu.Lock()
-			results = append(results, aurPkgs...)
-			mu.Unlock()
+		query, search = strings.TrimSpace(path), backend.FileOwners
+	} else {
+		var by SearchBy
+		by, query = ParseSearchQuery(query)
+		search = func(ctx context.Context, query string) ([]Package, error) {
+			return backend.Search(ctx, source, query, by)
 		}
-	})
-
-	wg.Wait()
+	}
+	if query == "" || (source == SourceAUR && utf8.RuneCountInString(query) < aurMinQuery) {
+		return nil, nil
+	}
 
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	results, err := search(ctx, query)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(results) > 0 {
 		checkInstalledStatus(results)
 	}
+	return results, nil
+}
 
-	sortPackages(results, query)
+// searchAll runs search for every source concurrently and ranks the merged
+// results for query.
+func searchAll(ctx context.Context, query string, search func(context.Context, SearchSource) ([]Package, error)) ([]Package, error) {
+	found := make([][]Package, len(SearchSources))
+	errs := make([]error, len(SearchSources))
+	var wg sync.WaitGroup
+	for i, source := range SearchSources {
+		wg.Go(func() {
+			found[i], errs[i] = search(ctx, source)
+		})
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(errs, nil) {
+		return nil, errors.Join(errs...)
+	}
+	results := slices.Concat(found...)
+	RankResults(results, query)
 	return results, nil
 }
 
+// RankResults orders results, e.g. merged from several sources, by how well
+// they match query with the current Scorer. Only name searches are ranked by
+// name; a file: query ranks by the file's base name.
+func RankResults(results []Package, query string) {
+	rankBy := ""
+	if path, ok := strings.CutPrefix(query, "file:"); ok {
+		rankBy = filepath.Base(strings.TrimSpace(path))
+	} else if by, term := ParseSearchQuery(query); by == ByName || by == ByNameDesc {
+		rankBy = term
+	}
+	rankPackages(results, rankBy)
+}
+
 func GetPackageDetails(p *Package) error {
+	return backend.Details(p)
+}
+
+func GetPKGBUILD(pkgName string) (string, error) {
+	return backend.PKGBUILD(pkgName)
+}
+
+func (b *ArchBackend) Search(ctx context.Context, source SearchSource, query string, by SearchBy) ([]Package, error) {
+	if source == SourceAUR {
+		return searchAURContext(ctx, query, by)
+	}
+
+	pkgs, err := b.searchDB(query, by)
+	if err == nil || (by != ByName && by != ByNameDesc) {
+		return pkgs, err
+	}
+	// pacman -Ss only searches names and descriptions.
+	cmd := exec.CommandContext(ctx, "pacman", "-Ss", "--", query)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		// Nothing matched.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsePacmanOutput(string(out), query, by == ByNameDesc), nil
+}
+
+func (b *ArchBackend) Details(p *Package) error {
 	if p.IsAUR {
 		return getAURDetails(p)
 	}
 
+	if err := b.detailsFromDB(p); err == nil {
+		return nil
+	}
+
 	flag := "-Si"
 	if p.IsInstalled {
 		flag = "-Qi"
@@ -124,16 +214,16 @@ func GetPackageDetails(p *Package) error {
 	return getPacmanDetails(p, flag)
 }
 
-func GetPKGBUILD(pkgName string) (string, error) {
-	urlStr := fmt.Sprintf("https://aur.archlinux.org/cgit/aur.git/plain/PKGBUILD?h=%s", url.QueryEscape(pkgName))
+func (b *ArchBackend) PKGBUILD(pkgName string) (string, error) {
+	urlStr := fmt.Sprintf("%s/cgit/aur.git/plain/PKGBUILD?h=%s", aurBaseURL, url.QueryEscape(pkgName))
 	resp, err := httpClient.Get(urlStr)
 	if err != nil {
-		return "", err
+		return "", netError(err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("failed to fetch PKGBUILD: %s", resp.Status)
+	if err := statusError(resp, "PKGBUILD"); err != nil {
+		return "", err
 	}
 
 	var sb strings.Builder
@@ -151,13 +241,6 @@ func GetPKGBUILD(pkgName string) (string, error) {
 }
 
 func getAURDetails(p *Package) error {
-	urlStr := fmt.Sprintf("https://aur.archlinux.org/rpc/?v=5&type=info&arg[]=%s", url.QueryEscape(p.Name))
-	resp, err := httpClient.Get(urlStr)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
 	type aurInfo struct {
 		Name           string   `json:"Name"`
 		Keywords       []string `json:"Keywords"`
@@ -178,20 +261,17 @@ func getAURDetails(p *Package) error {
 		Description    string   `json:"Description"`
 		Version        string   `json:"Version"`
 	}
-	type response struct {
-		Results []aurInfo `json:"results"`
-	}
-
-	var data response
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	params := url.Values{"v": {"5"}, "type": {"info"}, "arg[]": {p.Name}}
+	results, err := aurRPC[aurInfo](context.Background(), params, "AUR details")
+	if err != nil {
 		return err
 	}
 
-	if len(data.Results) == 0 {
+	if len(results) == 0 {
 		return fmt.Errorf("no info found for %s", p.Name)
 	}
 
-	info := data.Results[0]
+	info := results[0]
 	p.Keywords = info.Keywords
 	p.Licenses = info.License
 	p.Depends = info.Depends
@@ -267,6 +347,8 @@ func getPacmanDetails(p *Package, flag string) error {
 		}
 
 		switch key {
+		case "Repository":
+			p.Repository = val
 		case "Architecture":
 			p.Architecture = val
 		case "URL":
@@ -291,6 +373,7 @@ func getPacmanDetails(p *Package, flag string) error {
 			p.DownloadSize = val
 		case "Installed Size":
 			p.InstalledSize = val
+			p.InstalledBytes = parseSize(val)
 		case "Packager":
 			p.Packager = val
 		case "Build Date":
@@ -321,6 +404,7 @@ func getPacmanDetails(p *Package, flag string) error {
 			}
 		case "Install Reason":
 			p.InstallReason = val
+			p.Dependency = strings.Contains(val, "dependency")
 		case "Validated By":
 			p.ValidatedBy = val
 		}
@@ -329,83 +413,44 @@ func getPacmanDetails(p *Package, flag string) error {
 	return nil
 }
 
-func sortPackages(pkgs []Package, query string) {
-	query = strings.ToLower(query)
-	sort.SliceStable(pkgs, func(i, j int) bool {
-		p1 := pkgs[i]
-		p2 := pkgs[j]
-		if p1.Name == query && p2.Name != query {
-			return true
-		}
-		if p2.Name == query && p1.Name != query {
-			return false
-		}
-		if !p1.IsAUR && p2.IsAUR {
-			return true
-		}
-		if p1.IsAUR && !p2.IsAUR {
-			return false
-		}
-		if p1.IsAUR && p2.IsAUR {
-			if p1.Votes != p2.Votes {
-				return p1.Votes > p2.Votes
-			}
-		}
-		if len(p1.Name) != len(p2.Name) {
-			return len(p1.Name) < len(p2.Name)
-		}
-		return p1.Name < p2.Name
-	})
-}
-
-func searchAURContext(ctx context.Context, query string) ([]Package, error) {
-	urlStr := fmt.Sprintf("https://aur.archlinux.org/rpc/?v=5&type=search&by=name&arg=%s", url.QueryEscape(query))
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
+func searchAURContext(ctx context.Context, query string, by SearchBy) ([]Package, error) {
 	type aurResult struct {
-		Name         string `json:"Name"`
-		Version      string `json:"Version"`
-		Description  string `json:"Description"`
-		NumVotes     int    `json:"NumVotes"`
-		URL          string `json:"URL"`
-		Maintainer   string `json:"Maintainer"`
-		LastModified int64  `json:"LastModified"`
-	}
-	type response struct {
-		Results []aurResult `json:"results"`
+		Name         string  `json:"Name"`
+		Version      string  `json:"Version"`
+		Description  string  `json:"Description"`
+		NumVotes     int     `json:"NumVotes"`
+		URL          string  `json:"URL"`
+		Maintainer   string  `json:"Maintainer"`
+		LastModified int64   `json:"LastModified"`
+		Popularity   float64 `json:"Popularity"`
 	}
-
-	var data response
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	params := url.Values{"v": {"5"}, "type": {"search"}, "by": {string(by)}, "arg": {query}}
+	results, err := aurRPC[aurResult](ctx, params, "AUR search")
+	if err != nil {
 		return nil, err
 	}
 
 	var pkgs []Package
-	for _, r := range data.Results {
+	for _, r := range results {
 		pkgs = append(pkgs, Package{
 			Name:         r.Name,
 			Version:      r.Version,
 			Description:  r.Description,
+			Repository:   "aur",
 			IsAUR:        true,
 			Votes:        r.NumVotes,
 			URL:          r.URL,
 			Maintainer:   r.Maintainer,
 			LastModified: r.LastModified,
+			Popularity:   r.Popularity,
 		})
 	}
 	return pkgs, nil
 }
 
-func parsePacmanOutput(raw string, query string) []Package {
+// parsePacmanOutput reads pacman -Ss output. pacman matches descriptions
+// too; those hits are dropped unless keepDesc is set.
+func parsePacmanOutput(raw string, query string, keepDesc bool) []Package {
 	var pkgs []Package
 	lines := strings.Split(raw, "\n")
 	lowQuery := strings.ToLower(query)
@@ -424,7 +469,7 @@ func parsePacmanOutput(raw string, query string) []Package {
 				}
 
 				name := nameSplit[1]
-				if !strings.Contains(strings.ToLower(name), lowQuery) {
+				if !keepDesc && !strings.Contains(strings.ToLower(name), lowQuery) {
 					if i+1 < len(lines) {
 						i++
 					}
@@ -442,6 +487,7 @@ func parsePacmanOutput(raw string, query string) []Package {
 					Name:        name,
 					Version:     ver,
 					Description: desc,
+					Repository:  nameSplit[0],
 					IsAUR:       false,
 					Maintainer:  "Arch Linux",
 				})
@@ -452,30 +498,46 @@ func parsePacmanOutput(raw string, query string) []Package {
 }
 
 var (
-	installedCache map[string]bool
+	installedCache map[string]string // name → installed version
+	installedPkgs  []Package
 	cacheMu        sync.RWMutex
 )
 
+// RefreshInstalledCache rereads the installed versions. The full installed
+// packages are left to LoadInstalledPackages.
 func RefreshInstalledCache() {
-	out, err := exec.Command("pacman", "-Qq").Output()
+	newCache, err := backend.Installed()
 	if err != nil {
 		return
 	}
-	newCache := make(map[string]bool)
-	for line := range strings.SplitSeq(string(out), "\n") {
-		if line != "" {
-			newCache[line] = true
-		}
-	}
 	cacheMu.Lock()
 	installedCache = newCache
 	cacheMu.Unlock()
 }
 
-func GetInstalledCache() map[string]bool {
+func (b *ArchBackend) Installed() (map[string]string, error) {
+	if installed, err := readLocalVersions(b.dbPath()); err == nil {
+		return installed, nil
+	}
+
+	out, err := exec.Command("pacman", "-Q").Output()
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]string)
+	for line := range strings.SplitSeq(string(out), "\n") {
+		if name, version, ok := strings.Cut(line, " "); ok {
+			installed[name] = version
+		}
+	}
+	return installed, nil
+}
+
+// GetInstalledCache returns a copy of the installed packages and versions.
+func GetInstalledCache() map[string]string {
 	cacheMu.RLock()
 	defer cacheMu.RUnlock()
-	copy := make(map[string]bool)
+	copy := make(map[string]string)
 	maps.Copy(copy, installedCache)
 	return copy
 }
@@ -491,8 +553,9 @@ func checkInstalledStatus(pkgs []Package) {
 	}
 
 	for i := range pkgs {
-		if installedCache[pkgs[i].Name] {
+		if version, ok := installedCache[pkgs[i].Name]; ok {
 			pkgs[i].IsInstalled = true
+			pkgs[i].InstalledVersion = version
 		}
 	}
 }
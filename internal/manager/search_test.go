@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestParsePacmanOutputKeepsRepository(t *testing.T) {
+	raw := `core/linux 6.10.1.arch1-1 [installed]
+    The Linux kernel and modules
+extra/linux-firmware 20240703.1a3ff1f0-1
+    Firmware files for Linux
+internal/linux 6.10.1.arch1-1.corp
+    Patched kernel
+`
+	pkgs := parsePacmanOutput(raw, "linux", false)
+	if len(pkgs) != 3 {
+		t.Fatalf("Expected 3 packages, got %d", len(pkgs))
+	}
+
+	expected := []string{"core", "extra", "internal"}
+	for i, p := range pkgs {
+		if p.Repository != expected[i] {
+			t.Errorf("Package %d: expected repository %q, got %q", i, expected[i], p.Repository)
+		}
+	}
+	if pkgs[1].Description != "Firmware files for Linux" {
+		t.Errorf("Unexpected description %q", pkgs[1].Description)
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	tests := []struct {
+		pkg      Package
+		expected string
+	}{
+		{Package{Name: "linux", Repository: "internal"}, "internal/linux"},
+		{Package{Name: "yay", Repository: "aur", IsAUR: true}, "yay"},
+		{Package{Name: "foo", Repository: "local"}, "foo"},
+		{Package{Name: "bar"}, "bar"},
+	}
+	for _, tt := range tests {
+		if got := tt.pkg.QualifiedName(); got != tt.expected {
+			t.Errorf("QualifiedName(%+v) = %q, expected %q", tt.pkg, got, tt.expected)
+		}
+	}
+}
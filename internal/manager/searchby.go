@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"slices"
+	"strings"
+)
+
+// SearchBy is the package field a search matches, named like the AUR RPC's
+// by= parameter.
+type SearchBy string
+
+const (
+	ByName         SearchBy = "name"
+	ByNameDesc     SearchBy = "name-desc"
+	ByMaintainer   SearchBy = "maintainer"
+	ByDepends      SearchBy = "depends"
+	ByMakeDepends  SearchBy = "makedepends"
+	ByOptDepends   SearchBy = "optdepends"
+	ByCheckDepends SearchBy = "checkdepends"
+	ByKeywords     SearchBy = "keywords"
+)
+
+// SearchPrefixes map query prefixes such as "dep:glibc" to fields.
+var SearchPrefixes = []struct {
+	Prefix string
+	By     SearchBy
+}{
+	{"name:", ByName},
+	{"desc:", ByNameDesc},
+	{"maint:", ByMaintainer},
+	{"dep:", ByDepends},
+	{"makedep:", ByMakeDepends},
+	{"optdep:", ByOptDepends},
+	{"checkdep:", ByCheckDepends},
+	{"kw:", ByKeywords},
+}
+
+// ParseSearchQuery splits a field prefix off query. Queries without one
+// search names.
+func ParseSearchQuery(query string) (SearchBy, string) {
+	for _, sp := range SearchPrefixes {
+		if rest, ok := strings.CutPrefix(query, sp.Prefix); ok {
+			return sp.By, strings.TrimSpace(rest)
+		}
+	}
+	return ByName, query
+}
+
+// matchesSearch applies a search to a package from the sync databases the
+// way the AUR applies it to its own: names and descriptions by substring,
+// dependencies and keywords exactly. Repository packages have no
+// maintainer or keywords, so their packager and groups stand in.
+func matchesSearch(p Package, by SearchBy, query string) bool {
+	lowQuery := strings.ToLower(query)
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), lowQuery)
+	}
+	hasDep := func(deps []string) bool {
+		return slices.ContainsFunc(deps, func(dep string) bool { return optDepName(dep) == query })
+	}
+
+	switch by {
+	case ByNameDesc:
+		return contains(p.Name) || contains(p.Description)
+	case ByMaintainer:
+		if p.IsAUR {
+			return strings.EqualFold(p.Maintainer, query)
+		}
+		return contains(p.Packager)
+	case ByDepends:
+		return hasDep(p.Depends)
+	case ByMakeDepends:
+		return hasDep(p.MakeDepends)
+	case ByOptDepends:
+		return hasDep(p.OptDepends)
+	case ByCheckDepends:
+		return hasDep(p.CheckDepends)
+	case ByKeywords:
+		return slices.ContainsFunc(append(slices.Clone(p.Keywords), p.Groups...), func(k string) bool {
+			return strings.EqualFold(k, query)
+		})
+	default:
+		return contains(p.Name)
+	}
+}
@@ -0,0 +1,94 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		by    SearchBy
+		term  string
+	}{
+		{"vim", ByName, "vim"},
+		{"desc:text editor", ByNameDesc, "text editor"},
+		{"maint: someone", ByMaintainer, "someone"},
+		{"dep:glibc", ByDepends, "glibc"},
+		{"makedep:cmake", ByMakeDepends, "cmake"},
+		{"optdep:python", ByOptDepends, "python"},
+		{"checkdep:pytest", ByCheckDepends, "pytest"},
+		{"kw:wayland", ByKeywords, "wayland"},
+		{"deps:glibc", ByName, "deps:glibc"},
+	}
+	for _, tt := range tests {
+		by, term := ParseSearchQuery(tt.query)
+		if by != tt.by || term != tt.term {
+			t.Errorf("ParseSearchQuery(%q) = %q, %q; expected %q, %q", tt.query, by, term, tt.by, tt.term)
+		}
+	}
+}
+
+func TestArchBackendSearchBy(t *testing.T) {
+	dbPath := t.TempDir()
+	writeSyncDB(t, dbPath, "extra", map[string]string{
+		"vim-9.1-1":     "%NAME%\nvim\n\n%VERSION%\n9.1-1\n\n%DESC%\nVi Improved, a text editor\n\n%DEPENDS%\nglibc>=2.38\n\n%OPTDEPENDS%\npython: Python 3 support\n\n%PACKAGER%\nAlice <alice@archlinux.org>\n",
+		"nano-8.0-1":    "%NAME%\nnano\n\n%VERSION%\n8.0-1\n\n%DESC%\nPico editor clone\n\n%DEPENDS%\nglibc\n\n%PACKAGER%\nBob <bob@archlinux.org>\n\n%GROUPS%\neditors\n",
+		"python-3.12-1": "%NAME%\npython\n\n%VERSION%\n3.12-1\n\n%DESC%\nThe Python language\n",
+	})
+
+	var gotBy []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBy = append(gotBy, r.URL.Query().Get("by"))
+		w.Write([]byte(`{"version":5,"type":"search","resultcount":1,"results":[{"Name":"vim-git","Version":"9.1-1"}]}`))
+	}))
+	defer srv.Close()
+	origURL := aurBaseURL
+	aurBaseURL = srv.URL
+	defer func() { aurBaseURL = origURL }()
+
+	b := &ArchBackend{DBPath: dbPath, ConfPath: filepath.Join(dbPath, "missing.conf")}
+	official := func(query string, by SearchBy) []string {
+		t.Helper()
+		if _, err := b.Search(context.Background(), SourceAUR, query, by); err != nil {
+			t.Fatalf("Search(aur, %q, %s) returned error: %v", query, by, err)
+		}
+		pkgs, err := b.Search(context.Background(), SourceRepos, query, by)
+		if err != nil {
+			t.Fatalf("Search(repos, %q, %s) returned error: %v", query, by, err)
+		}
+		var names []string
+		for _, p := range pkgs {
+			names = append(names, p.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	if got := official("editor", ByName); got != nil {
+		t.Errorf("Expected no name matches for editor, got %v", got)
+	}
+	if got := official("editor", ByNameDesc); !slices.Equal(got, []string{"nano", "vim"}) {
+		t.Errorf("Expected both editors by description, got %v", got)
+	}
+	if got := official("glibc", ByDepends); !slices.Equal(got, []string{"nano", "vim"}) {
+		t.Errorf("Expected both editors to depend on glibc, got %v", got)
+	}
+	if got := official("python", ByOptDepends); !slices.Equal(got, []string{"vim"}) {
+		t.Errorf("Expected vim to optionally depend on python, got %v", got)
+	}
+	if got := official("alice", ByMaintainer); !slices.Equal(got, []string{"vim"}) {
+		t.Errorf("Expected vim to be packaged by Alice, got %v", got)
+	}
+	if got := official("editors", ByKeywords); !slices.Equal(got, []string{"nano"}) {
+		t.Errorf("Expected the editors group to match as a keyword, got %v", got)
+	}
+
+	if !slices.Equal(gotBy, []string{"name", "name-desc", "depends", "optdepends", "maintainer", "keywords"}) {
+		t.Errorf("Unexpected AUR search fields %v", gotBy)
+	}
+}
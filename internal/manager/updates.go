@@ -0,0 +1,281 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// aurInfoBatch keeps multi-arg info requests well under URL length limits.
+const aurInfoBatch = 100
+
+// CheckUpdates lists installed packages that have a newer version available.
+// Version holds the new version and InstalledVersion the current one. An
+// *UpdatesWarning comes with the updates that could still be checked.
+func CheckUpdates(ctx context.Context) ([]Package, error) {
+	return backend.Updates(ctx)
+}
+
+// UpdatesWarning explains why an update check is incomplete. Stale is why
+// the sync databases couldn't be refreshed, so repository updates may be out
+// of date; AUR is why foreign packages couldn't be checked.
+type UpdatesWarning struct {
+	Stale error
+	AUR   error
+}
+
+func (w *UpdatesWarning) Error() string {
+	var parts []string
+	if w.Stale != nil {
+		parts = append(parts, "sync databases may be out of date: "+w.Stale.Error())
+	}
+	if w.AUR != nil {
+		parts = append(parts, "AUR packages not checked: "+w.AUR.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (w *UpdatesWarning) Unwrap() []error {
+	var errs []error
+	for _, err := range []error{w.Stale, w.AUR} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Updates compares the local database against a freshly synced copy of the
+// sync databases, like checkupdates, and foreign packages against the AUR.
+func (b *ArchBackend) Updates(ctx context.Context) ([]Package, error) {
+	local, err := b.localPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var warn UpdatesWarning
+	syncDB := b
+	if tmp, err := b.refreshTempDB(ctx); err == nil {
+		syncDB = &ArchBackend{DBPath: tmp, ConfPath: b.ConfPath, TempDBPath: tmp}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else {
+		warn.Stale = err
+	}
+	remote, err := syncDB.syncPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	// First match wins, following pacman.conf repository order.
+	available := make(map[string]Package, len(remote))
+	for _, p := range remote {
+		if _, ok := available[p.Name]; !ok {
+			available[p.Name] = p
+		}
+	}
+
+	var (
+		updates []Package
+		foreign []Package
+	)
+	for _, p := range local {
+		sp, ok := available[p.Name]
+		if !ok {
+			foreign = append(foreign, p)
+			continue
+		}
+		if Vercmp(sp.Version, p.Version) > 0 {
+			updates = append(updates, Package{
+				Name:             p.Name,
+				Version:          sp.Version,
+				InstalledVersion: p.Version,
+				Description:      sp.Description,
+				Repository:       sp.Repository,
+				Maintainer:       sp.Maintainer,
+				IsInstalled:      true,
+			})
+		}
+	}
+
+	if len(foreign) > 0 {
+		names := make([]string, len(foreign))
+		for i, p := range foreign {
+			names[i] = p.Name
+		}
+		aurPkgs, err := aurInfo(ctx, names)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		warn.AUR = err
+		for _, p := range foreign {
+			ap, ok := aurPkgs[p.Name]
+			if !ok || Vercmp(ap.Version, p.Version) <= 0 {
+				continue
+			}
+			ap.InstalledVersion = p.Version
+			ap.IsInstalled = true
+			updates = append(updates, ap)
+		}
+	}
+
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Name < updates[j].Name })
+	if warn.Stale != nil || warn.AUR != nil {
+		return updates, &warn
+	}
+	return updates, nil
+}
+
+// privateDir creates dir for the current user alone, or makes sure an
+// existing one is a directory of theirs that nobody else can write to, like
+// checkupdates does with CHECKUPDATES_DB.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", dir)
+	}
+	if info.Mode().Perm() != 0700 {
+		return os.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// refreshTempDB syncs a private copy of the databases so checking for updates
+// never touches /var/lib/pacman/sync and can't lead to a partial upgrade.
+func (b *ArchBackend) refreshTempDB(ctx context.Context) (string, error) {
+	tmp := b.TempDBPath
+	if tmp == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		tmp = filepath.Join(cache, "gopac", "db")
+	}
+	if err := privateDir(tmp); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(tmp, "sync"), 0755); err != nil {
+		return "", err
+	}
+
+	link := filepath.Join(tmp, "local")
+	target := filepath.Join(b.dbPath(), "local")
+	if current, err := os.Readlink(link); err != nil || current != target {
+		os.Remove(link)
+		if err := os.Symlink(target, link); err != nil {
+			return "", err
+		}
+	}
+
+	// Seed the copy with the current databases so pacman only downloads
+	// what changed.
+	paths, _ := filepath.Glob(filepath.Join(b.dbPath(), "sync", "*.db"))
+	for _, src := range paths {
+		dst := filepath.Join(tmp, "sync", filepath.Base(src))
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return "", err
+		}
+	}
+
+	args := []string{"pacman", "-Sy", "--dbpath", tmp, "--logfile", "/dev/null"}
+	if _, err := exec.LookPath("fakeroot"); err == nil {
+		args = append([]string{"fakeroot", "--"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("syncing temporary database: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return tmp, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// aurInfo fetches basic info for the given names, keyed by name. Names that
+// aren't in the AUR are simply missing from the result.
+func aurInfo(ctx context.Context, names []string) (map[string]Package, error) {
+	type aurResult struct {
+		Name         string   `json:"Name"`
+		Version      string   `json:"Version"`
+		Description  string   `json:"Description"`
+		NumVotes     int      `json:"NumVotes"`
+		URL          string   `json:"URL"`
+		Maintainer   string   `json:"Maintainer"`
+		LastModified int64    `json:"LastModified"`
+		Depends      []string `json:"Depends"`
+		MakeDepends  []string `json:"MakeDepends"`
+		OptDepends   []string `json:"OptDepends"`
+		Provides     []string `json:"Provides"`
+		License      []string `json:"License"`
+		Popularity   float64  `json:"Popularity"`
+	}
+	pkgs := make(map[string]Package)
+	for start := 0; start < len(names); start += aurInfoBatch {
+		end := min(start+aurInfoBatch, len(names))
+
+		params := url.Values{"v": {"5"}, "type": {"info"}}
+		for _, name := range names[start:end] {
+			params.Add("arg[]", name)
+		}
+		results, err := aurRPC[aurResult](ctx, params, "AUR info")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range results {
+			pkgs[r.Name] = Package{
+				Name:         r.Name,
+				Version:      r.Version,
+				Description:  r.Description,
+				Repository:   "aur",
+				IsAUR:        true,
+				Votes:        r.NumVotes,
+				URL:          r.URL,
+				Maintainer:   r.Maintainer,
+				LastModified: r.LastModified,
+				Depends:      r.Depends,
+				MakeDepends:  r.MakeDepends,
+				OptDepends:   r.OptDepends,
+				Provides:     r.Provides,
+				Licenses:     r.License,
+				Popularity:   r.Popularity,
+			}
+		}
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchBackendUpdates(t *testing.T) {
+	dbPath := t.TempDir()
+	writeLocalEntry(t, dbPath, "bash", "5.2.026-1", "%NAME%\nbash\n\n%VERSION%\n5.2.026-1\n\n%INSTALLDATE%\n1\n")
+	writeLocalEntry(t, dbPath, "glibc", "2.40-1", "%NAME%\nglibc\n\n%VERSION%\n2.40-1\n\n%INSTALLDATE%\n1\n")
+	writeLocalEntry(t, dbPath, "yay", "12.3.4-1", "%NAME%\nyay\n\n%VERSION%\n12.3.4-1\n\n%INSTALLDATE%\n1\n")
+	writeLocalEntry(t, dbPath, "my-script", "1.0-1", "%NAME%\nmy-script\n\n%VERSION%\n1.0-1\n\n%INSTALLDATE%\n1\n")
+	writeSyncDB(t, dbPath, "core", map[string]string{
+		"bash-5.2.026-2": "%NAME%\nbash\n\n%VERSION%\n5.2.026-2\n",
+		"glibc-2.40-1":   "%NAME%\nglibc\n\n%VERSION%\n2.40-1\n",
+	})
+
+	aurDown := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if aurDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		args := r.URL.Query()["arg[]"]
+		if len(args) != 2 {
+			t.Errorf("Expected 2 foreign packages in the info request, got %v", args)
+		}
+		w.Write([]byte(`{"version":5,"type":"multiinfo","resultcount":1,"results":[{"Name":"yay","Version":"12.3.5-1"}]}`))
+	}))
+	defer srv.Close()
+	origURL := aurBaseURL
+	aurBaseURL = srv.URL
+	defer func() { aurBaseURL = origURL }()
+
+	// No pacman on PATH: the temporary sync fails and the current sync
+	// databases are used instead, with a warning.
+	t.Setenv("PATH", t.TempDir())
+
+	b := &ArchBackend{
+		DBPath:     dbPath,
+		ConfPath:   filepath.Join(dbPath, "missing.conf"),
+		TempDBPath: filepath.Join(t.TempDir(), "checkup-db"),
+	}
+	updates, err := b.Updates(context.Background())
+	var warn *UpdatesWarning
+	if !errors.As(err, &warn) || warn.Stale == nil || warn.AUR != nil {
+		t.Fatalf("Expected a stale database warning, got %v", err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("Expected 2 updates, got %+v", updates)
+	}
+
+	if u := updates[0]; u.Name != "bash" || u.Repository != "core" || u.InstalledVersion != "5.2.026-1" || u.Version != "5.2.026-2" {
+		t.Errorf("Unexpected repo update: %+v", u)
+	}
+	if u := updates[1]; u.Name != "yay" || !u.IsAUR || u.InstalledVersion != "12.3.4-1" || u.Version != "12.3.5-1" {
+		t.Errorf("Unexpected AUR update: %+v", u)
+	}
+
+	// The repository updates survive the AUR failing.
+	aurDown = true
+	updates, err = b.Updates(context.Background())
+	if !errors.As(err, &warn) || warn.AUR == nil {
+		t.Fatalf("Expected an AUR warning, got %v", err)
+	}
+	if len(updates) != 1 || updates[0].Name != "bash" {
+		t.Errorf("Expected the bash update despite the AUR, got %+v", updates)
+	}
+}
+
+func TestPrivateDir(t *testing.T) {
+	base := t.TempDir()
+
+	dir := filepath.Join(base, "db")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := privateDir(dir); err != nil {
+		t.Fatalf("privateDir() returned error: %v", err)
+	}
+	if info, _ := os.Stat(dir); info.Mode().Perm() != 0700 {
+		t.Errorf("Expected mode 0700, got %v", info.Mode().Perm())
+	}
+
+	// A link planted in place of the directory is refused.
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Fatal(err)
+	}
+	if err := privateDir(link); err == nil {
+		t.Error("Expected a symlink to be refused")
+	}
+}
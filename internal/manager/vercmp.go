@@ -0,0 +1,148 @@
+package manager
+
+import (
+	"strings"
+)
+
+// Vercmp compares two package versions the way pacman's alpm_pkg_vercmp does.
+// It returns -1 if a is older than b, 0 if they are equal and 1 if a is newer.
+// Versions have the form [epoch:]pkgver[-pkgrel]; a missing epoch counts as 0
+// and pkgrel is only compared when both sides have one.
+func Vercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	epoch1, ver1, rel1 := parseEVR(a)
+	epoch2, ver2, rel2 := parseEVR(b)
+
+	ret := rpmvercmp(epoch1, epoch2)
+	if ret == 0 {
+		ret = rpmvercmp(ver1, ver2)
+		if ret == 0 && rel1 != "" && rel2 != "" {
+			ret = rpmvercmp(rel1, rel2)
+		}
+	}
+	return ret
+}
+
+// parseEVR splits a version into epoch, version and release.
+func parseEVR(evr string) (epoch, version, release string) {
+	s := 0
+	for s < len(evr) && isDigit(evr[s]) {
+		s++
+	}
+
+	epoch, version = "0", evr
+	if s < len(evr) && evr[s] == ':' {
+		if s > 0 {
+			epoch = evr[:s]
+		}
+		version = evr[s+1:]
+	}
+
+	if i := strings.LastIndexByte(version, '-'); i >= 0 {
+		version, release = version[:i], version[i+1:]
+	}
+	return epoch, version, release
+}
+
+// rpmvercmp compares alternating runs of digits and letters, ignoring the
+// separators between them except for their length.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	one, two := 0, 0
+	ptr1, ptr2 := 0, 0
+
+	for one < len(a) && two < len(b) {
+		for one < len(a) && !isAlnum(a[one]) {
+			one++
+		}
+		for two < len(b) && !isAlnum(b[two]) {
+			two++
+		}
+
+		// If we ran to the end of either, we are finished with the loop.
+		if one >= len(a) || two >= len(b) {
+			break
+		}
+
+		// If the separator lengths were different, we are also finished.
+		if one-ptr1 != two-ptr2 {
+			if one-ptr1 < two-ptr2 {
+				return -1
+			}
+			return 1
+		}
+
+		ptr1, ptr2 = one, two
+
+		// Grab the first completely alpha or completely numeric segment.
+		isNum := isDigit(a[ptr1])
+		if isNum {
+			for ptr1 < len(a) && isDigit(a[ptr1]) {
+				ptr1++
+			}
+			for ptr2 < len(b) && isDigit(b[ptr2]) {
+				ptr2++
+			}
+		} else {
+			for ptr1 < len(a) && isAlpha(a[ptr1]) {
+				ptr1++
+			}
+			for ptr2 < len(b) && isAlpha(b[ptr2]) {
+				ptr2++
+			}
+		}
+
+		seg1, seg2 := a[one:ptr1], b[two:ptr2]
+
+		// Numeric segments are always newer than alpha segments.
+		if seg2 == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			seg1 = strings.TrimLeft(seg1, "0")
+			seg2 = strings.TrimLeft(seg2, "0")
+
+			// Whichever number has more digits wins.
+			if len(seg1) > len(seg2) {
+				return 1
+			}
+			if len(seg2) > len(seg1) {
+				return -1
+			}
+		}
+
+		if c := strings.Compare(seg1, seg2); c != 0 {
+			return c
+		}
+
+		one, two = ptr1, ptr2
+	}
+
+	// All segments compared identically but the separators were different.
+	if one >= len(a) && two >= len(b) {
+		return 0
+	}
+
+	// A remaining alpha string never beats an empty string: if a is empty and
+	// b is not alpha, or if a is alpha, b is newer.
+	if (one >= len(a) && !isAlpha(b[two])) || (one < len(a) && isAlpha(a[one])) {
+		return -1
+	}
+	return 1
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+
+func isAlnum(c byte) bool { return isDigit(c) || isAlpha(c) }
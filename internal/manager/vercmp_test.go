@@ -0,0 +1,84 @@
+package manager
+
+import "testing"
+
+// Cases from pacman's test/util/vercmptest.sh. Each is also checked reversed.
+func TestVercmp(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		// all similar length, no pkgrel
+		{"1.5.0", "1.5.0", 0},
+		{"1.5.1", "1.5.0", 1},
+		// mixed length
+		{"1.5.1", "1.5", 1},
+		// with pkgrel, simple
+		{"1.5.0-1", "1.5.0-1", 0},
+		{"1.5.0-1", "1.5.0-2", -1},
+		{"1.5.0-1", "1.5.1-1", -1},
+		{"1.5.0-2", "1.5.1-1", -1},
+		// with pkgrel, mixed lengths
+		{"1.5-1", "1.5.1-1", -1},
+		{"1.5-2", "1.5.1-1", -1},
+		{"1.5-2", "1.5.1-2", -1},
+		// mixed pkgrel inclusion
+		{"1.5", "1.5-1", 0},
+		{"1.5-1", "1.5", 0},
+		{"1.1-1", "1.1", 0},
+		{"1.0-1", "1.1", -1},
+		{"1.1-1", "1.0", 1},
+		// alphanumeric versions
+		{"1.5b-1", "1.5-1", -1},
+		{"1.5b", "1.5", -1},
+		{"1.5b-1", "1.5", -1},
+		{"1.5b", "1.5.1", -1},
+		// from the manpage
+		{"1.0a", "1.0alpha", -1},
+		{"1.0alpha", "1.0b", -1},
+		{"1.0b", "1.0beta", -1},
+		{"1.0beta", "1.0rc", -1},
+		{"1.0rc", "1.0", -1},
+		// alpha-dotted versions
+		{"1.5.a", "1.5", 1},
+		{"1.5.b", "1.5.a", 1},
+		{"1.5.1", "1.5.b", 1},
+		// alpha dots and dashes
+		{"1.5.b-1", "1.5.b", 0},
+		{"1.5-1", "1.5.b", -1},
+		// same/similar content, differing separators
+		{"2.0", "2_0", 0},
+		{"2.0_a", "2_0.a", 0},
+		{"2.0a", "2.0.a", -1},
+		{"2___a", "2_a", 1},
+		// epoch included version comparisons
+		{"0:1.0", "0:1.0", 0},
+		{"0:1.0", "0:1.1", -1},
+		{"1:1.0", "0:1.0", 1},
+		{"1:1.0", "0:1.1", 1},
+		{"1:1.0", "2:1.1", -1},
+		// epoch + sometimes present pkgrel
+		{"1:1.0", "0:1.0-1", 1},
+		{"1:1.0-1", "0:1.1-1", 1},
+		// epoch included on one version
+		{"0:1.0", "1.0", 0},
+		{"0:1.0", "1.1", -1},
+		{"0:1.1", "1.0", 1},
+		{"1:1.0", "1.0", 1},
+		{"1:1.0", "1.1", 1},
+		{"1:1.1", "1.1", 1},
+		// leading zeros and real-world versions
+		{"1.010", "1.9", 1},
+		{"6.10.1.arch1-1", "6.9.12.arch1-1", 1},
+		{"r1234.abcdef-1", "r999.fedcba-1", 1},
+	}
+
+	for _, tt := range tests {
+		if got := Vercmp(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Vercmp(%q, %q) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+		if got := Vercmp(tt.b, tt.a); got != -tt.expected {
+			t.Errorf("Vercmp(%q, %q) = %d, expected %d", tt.b, tt.a, got, -tt.expected)
+		}
+	}
+}
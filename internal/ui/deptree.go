@@ -0,0 +1,311 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type depTreeMsg struct {
+	key     string
+	reverse bool
+	tree    *manager.DepNode
+	err     error
+}
+
+// treeRow is a visible line of the dependency tree.
+type treeRow struct {
+	node   *manager.DepNode
+	path   string // names from the root, e.g. "firefox/gtk3/glib2"
+	depth  int
+	prefix string // box-drawing guides for the ancestors
+	last   bool
+}
+
+// openDepTree shows p's dependency tree, or with reverse set the packages
+// that require it.
+func (m *Model) openDepTree(p manager.Package, reverse bool) tea.Cmd {
+	key := p.QualifiedName()
+	m.showingTree = true
+	m.showingPKGBUILD = false
+	m.showingFiles = false
+	m.treeCursor = 0
+	if m.depTreeFor == key && m.treeReverse == reverse && (m.depTree != nil || m.loadingTree) {
+		return nil
+	}
+	m.depTreeFor = key
+	m.treeReverse = reverse
+	m.depTree = nil
+	m.treeErr = ""
+	m.treeExpanded = make(map[string]bool)
+	m.loadingTree = true
+	return func() tea.Msg {
+		var (
+			tree *manager.DepNode
+			err  error
+		)
+		if reverse {
+			tree, err = manager.ReverseDependencyTree(p.Name)
+		} else {
+			tree, err = manager.DependencyTree(context.Background(), p)
+		}
+		return depTreeMsg{key: key, reverse: reverse, tree: tree, err: err}
+	}
+}
+
+func (m Model) handleDepTree(msg depTreeMsg) Model {
+	if msg.key != m.depTreeFor || msg.reverse != m.treeReverse {
+		return m
+	}
+	m.loadingTree = false
+	m.depTree = msg.tree
+	if msg.err != nil {
+		m.treeErr = msg.err.Error()
+	}
+	return m
+}
+
+// treeRows flattens the expanded part of the tree. Only the root is expanded
+// until the user opens more.
+func (m Model) treeRows() []treeRow {
+	if m.depTree == nil {
+		return nil
+	}
+	var rows []treeRow
+	var walk func(n *manager.DepNode, path, prefix string, depth int, last bool)
+	walk = func(n *manager.DepNode, path, prefix string, depth int, last bool) {
+		rows = append(rows, treeRow{node: n, path: path, depth: depth, prefix: prefix, last: last})
+		if !m.isExpanded(path, depth) {
+			return
+		}
+		childPrefix := prefix
+		if depth > 0 {
+			if last {
+				childPrefix += "   "
+			} else {
+				childPrefix += "│  "
+			}
+		}
+		for i, c := range n.Children {
+			walk(c, path+"/"+c.Name(), childPrefix, depth+1, i == len(n.Children)-1)
+		}
+	}
+	walk(m.depTree, m.depTree.Name(), "", 0, true)
+	return rows
+}
+
+func (m Model) isExpanded(path string, depth int) bool {
+	if open, ok := m.treeExpanded[path]; ok {
+		return open
+	}
+	return depth == 0
+}
+
+// updateDepTree handles keys while the tree has focus in the detail panel.
+func (m Model) updateDepTree(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rows := m.treeRows()
+	switch msg.String() {
+	case "up", "k":
+		if m.treeCursor > 0 {
+			m.treeCursor--
+		}
+	case "down", "j":
+		if m.treeCursor < len(rows)-1 {
+			m.treeCursor++
+		}
+	case " ", "right", "l", "left", "h":
+		if m.treeCursor >= len(rows) {
+			return m, nil
+		}
+		row := rows[m.treeCursor]
+		open := m.isExpanded(row.path, row.depth)
+		switch msg.String() {
+		case "right", "l":
+			open = true
+		case "left", "h":
+			open = false
+		default:
+			open = !open
+		}
+		m.treeExpanded[row.path] = open
+	case "e":
+		for _, row := range rows {
+			m.treeExpanded[row.path] = true
+		}
+	case "enter":
+		if m.treeCursor < len(rows) && m.treeCursor > 0 {
+			m.showingTree = false
+			return m, m.jumpToPackage(rows[m.treeCursor].node.Name())
+		}
+	case "esc":
+		m.showingTree = false
+		return m, nil
+	default:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	m.viewport.SetContent(m.renderDepTree(m.viewport.Width))
+	line := lipgloss.Height(m.treeHeader(m.viewport.Width)) - 1 + m.treeCursor
+	if line < m.viewport.YOffset {
+		m.viewport.SetYOffset(line)
+	} else if line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height + 1)
+	}
+	return m, nil
+}
+
+func depStatusStyle(s manager.DepStatus) (string, lipgloss.Style) {
+	switch s {
+	case manager.DepInstalled:
+		return "✓", lipgloss.NewStyle().Foreground(CurrentTheme.Green)
+	case manager.DepRepo:
+		return "↓", lipgloss.NewStyle().Foreground(CurrentTheme.RepoOfficial)
+	case manager.DepAUR:
+		return "↓", lipgloss.NewStyle().Foreground(CurrentTheme.RepoAUR)
+	default:
+		return "✗", lipgloss.NewStyle().Foreground(CurrentTheme.Red)
+	}
+}
+
+// treeHeader is everything above the rows: the title and either the size of
+// the closure or why the package is installed. It ends with a newline.
+func (m Model) treeHeader(width int) string {
+	var sb strings.Builder
+	headerStyle := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Background(CurrentTheme.Highlight).Padding(0, 1)
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+
+	name := m.depTreeFor
+	if m.depTree != nil {
+		name = m.depTree.Name()
+	}
+	title := "Dependencies of " + name
+	if m.treeReverse {
+		title = "Required by: " + name
+	}
+	fmt.Fprintf(&sb, "\n%s\n\n", headerStyle.Render(title))
+
+	switch {
+	case m.loadingTree:
+		sb.WriteString("Resolving dependencies...\n")
+	case m.treeErr != "":
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Render("Error: "+m.treeErr) + "\n")
+	case m.depTree == nil:
+	case m.treeReverse:
+		sb.WriteString(whyInstalled(m.depTree))
+		sb.WriteByte('\n')
+	default:
+		stats := m.depTree.Stats()
+		summary := fmt.Sprintf("%d packages, %s • %d to install, %s", stats.Packages,
+			manager.FormatSize(stats.TotalBytes), stats.ToInstall, manager.FormatSize(stats.InstallBytes))
+		if len(stats.Missing) > 0 {
+			summary += fmt.Sprintf(" • %d missing", len(stats.Missing))
+		}
+		sb.WriteString(gray.Render(summary))
+		sb.WriteByte('\n')
+		if err := m.depTree.AURErr; err != nil {
+			sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Orange).Render("AUR unavailable, AUR dependencies show as missing: "+err.Error()) + "\n")
+		}
+		sb.WriteByte('\n')
+	}
+	return lipgloss.NewStyle().Width(width).Render(sb.String())
+}
+
+// whyInstalled explains a reverse tree: "Installed because of firefox → gtk3
+// → glib2", one chain per line.
+func whyInstalled(root *manager.DepNode) string {
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+	if !root.Package.Dependency {
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Green).Render("Explicitly installed") + "\n"
+	}
+
+	chains := manager.WhyInstalled(root)
+	if len(chains) == 0 {
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Orange).Render("Installed as a dependency, but nothing requires it (orphan)") + "\n"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(gray.Render("Installed because of:") + "\n")
+	const shown = 5
+	for _, chain := range chains[:min(shown, len(chains))] {
+		explicit := lipgloss.NewStyle().Foreground(CurrentTheme.Green).Bold(true).Render(chain[0])
+		sb.WriteString("  " + strings.Join(append([]string{explicit}, chain[1:]...), " → ") + "\n")
+	}
+	if len(chains) > shown {
+		sb.WriteString(gray.Render(fmt.Sprintf("  and %d more", len(chains)-shown)) + "\n")
+	}
+	return sb.String()
+}
+
+func (m Model) renderDepTree(width int) string {
+	var sb strings.Builder
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+
+	sb.WriteString(m.treeHeader(width))
+	if m.depTree == nil {
+		return sb.String()
+	}
+
+	for i, row := range m.treeRows() {
+		n := row.node
+		guide := ""
+		if row.depth > 0 {
+			guide = row.prefix + "├─ "
+			if row.last {
+				guide = row.prefix + "└─ "
+			}
+		}
+
+		toggle := "  "
+		if len(n.Children) > 0 {
+			toggle = "▸ "
+			if m.isExpanded(row.path, row.depth) {
+				toggle = "▾ "
+			}
+		}
+
+		icon, style := depStatusStyle(n.Status)
+		label := n.Name()
+		if n.Provider {
+			label = fmt.Sprintf("%s → %s", n.Dep, n.Name())
+		} else if n.Dep != n.Name() && row.depth > 0 {
+			label = n.Dep
+		}
+		if i == m.treeCursor {
+			label = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true).Render(label)
+		} else {
+			label = lipgloss.NewStyle().Foreground(CurrentTheme.Text).Render(label)
+		}
+
+		var notes []string
+		if m.treeReverse && row.depth > 0 && !n.Package.Dependency {
+			notes = append(notes, "explicit")
+		}
+		if row.depth > 0 && n.Status != manager.DepInstalled {
+			notes = append(notes, n.Status.String())
+		}
+		if n.Cycle {
+			notes = append(notes, "cycle")
+		} else if n.Seen {
+			notes = append(notes, "see above")
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = " " + gray.Render("("+strings.Join(notes, ", ")+")")
+		}
+
+		// Rows are cut rather than wrapped so each is one line.
+		line := fmt.Sprintf("%s%s%s %s%s", gray.Render(guide), gray.Render(toggle), style.Render(icon), label, note)
+		sb.WriteString(lipgloss.NewStyle().MaxWidth(width).Render(line))
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString(gray.Render("\n[ ↑/↓: Move • Space/←/→: Collapse/Expand • e: Expand All • Enter: Open • x/X: Export • Esc: Back ]"))
+	return lipgloss.NewStyle().Width(width).Render(sb.String())
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type cachedVersionsMsg struct {
+	name     string
+	versions []manager.CachedPackage
+	err      error
+}
+
+// openDowngrade lists the cached versions of an installed package.
+func (m *Model) openDowngrade(p manager.Package) tea.Cmd {
+	m.downgradePkg = p
+	m.downgradeVersions = nil
+	m.downgradeIdx = 0
+	m.downgradeErr = ""
+	m.showingDowngrade = true
+	m.loadingDowngrade = true
+	return func() tea.Msg {
+		versions, err := manager.CachedVersions(p.Name)
+		return cachedVersionsMsg{name: p.Name, versions: versions, err: err}
+	}
+}
+
+func (m Model) handleCachedVersions(msg cachedVersionsMsg) Model {
+	if msg.name != m.downgradePkg.Name {
+		return m
+	}
+	m.loadingDowngrade = false
+	m.downgradeVersions = msg.versions
+	if msg.err != nil {
+		m.downgradeErr = msg.err.Error()
+	}
+	// Start on the newest version older than the installed one. Version is
+	// the sync one when an update is pending.
+	installed := cmp.Or(m.downgradePkg.InstalledVersion, m.downgradePkg.Version)
+	for i, v := range msg.versions {
+		if manager.Vercmp(v.Version, installed) < 0 {
+			m.downgradeIdx = i
+			break
+		}
+	}
+	return m
+}
+
+func (m Model) updateDowngrade(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.downgradeIdx > 0 {
+			m.downgradeIdx--
+		}
+	case "down", "j":
+		if m.downgradeIdx < len(m.downgradeVersions)-1 {
+			m.downgradeIdx++
+		}
+	case "i":
+		name := m.downgradePkg.Name
+		manager.IgnoreForSession(name, !manager.IsSessionIgnored(name))
+		m.heldBack[name] = manager.IsSessionIgnored(name)
+		m.updateListItems()
+	case "enter":
+		if m.downgradeIdx >= len(m.downgradeVersions) {
+			return m, nil
+		}
+		m.showingDowngrade = false
+		c := manager.DowngradeCmd(m.downgradeVersions[m.downgradeIdx].Path)
+		return m, m.execTransaction(c, func(err error) tea.Msg { return refreshInstalledStatus() })
+	case "esc", "q", "d":
+		m.showingDowngrade = false
+	}
+	return m, nil
+}
+
+func (m Model) downgradeView() string {
+	p := m.downgradePkg
+	title := HeaderStyle.Render(" DOWNGRADE " + strings.ToUpper(p.Name) + " ")
+
+	var sb strings.Builder
+	sb.WriteByte('\n')
+	sb.WriteString(title)
+	sb.WriteString("\n\n")
+
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+	switch {
+	case m.loadingDowngrade:
+		sb.WriteString(m.spinner.View() + " Looking through the package cache...\n")
+	case m.downgradeErr != "":
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Render(m.downgradeErr) + "\n")
+	case len(m.downgradeVersions) == 0:
+		sb.WriteString(gray.Render("No cached versions of "+p.Name+" found.") + "\n")
+	}
+
+	for i, v := range m.downgradeVersions {
+		style := lipgloss.NewStyle().Foreground(CurrentTheme.Text)
+		cursor := "  "
+		if i == m.downgradeIdx {
+			style = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true)
+			cursor = "▶ "
+		}
+		built := "unknown build date"
+		if v.BuildDate > 0 {
+			built = "built " + time.Unix(v.BuildDate, 0).Format("2006-01-02")
+		}
+		note := ""
+		if v.Version == cmp.Or(p.InstalledVersion, p.Version) {
+			note = lipgloss.NewStyle().Foreground(CurrentTheme.Green).Render(" (installed)")
+		}
+		fmt.Fprintf(&sb, "%s%s %s%s\n", style.Render(cursor), style.Render(fmt.Sprintf("%-24s", v.Version)), gray.Render(built), note)
+	}
+
+	ignore := "i: Ignore in upgrades this session"
+	if manager.IsSessionIgnored(p.Name) {
+		ignore = "i: Stop ignoring " + p.Name
+	}
+	sb.WriteByte('\n')
+	sb.WriteString(gray.Render("Enter: Install with pacman -U • " + ignore + " • Esc: Cancel"))
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+		lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(CurrentTheme.Focus).
+			Padding(1, 4).
+			Render(sb.String()))
+}
@@ -0,0 +1,360 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopac/internal/manager"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type filesMsg struct {
+	key   string
+	files []string
+	err   error
+}
+
+// fileNode is a file or directory in the Files view. Directory paths end in
+// "/", like in the package databases.
+type fileNode struct {
+	name     string
+	path     string
+	dir      bool
+	count    int // files anywhere below a directory
+	children []*fileNode
+}
+
+// fileRow is a visible line of the file tree.
+type fileRow struct {
+	node   *fileNode
+	depth  int
+	prefix string
+	last   bool
+}
+
+// openFiles shows the files p installs, loading the list the first time.
+func (m *Model) openFiles(p manager.Package) tea.Cmd {
+	key := p.QualifiedName()
+	m.showingFiles = true
+	m.showingTree = false
+	m.showingPKGBUILD = false
+	m.filesCursor = 0
+	if m.filesFor == key && (m.files != nil || m.loadingFiles) {
+		return nil
+	}
+	m.filesFor = key
+	m.files = nil
+	m.filesTree = nil
+	m.filesErr = ""
+	m.filesExpanded = make(map[string]bool)
+	m.filesInput.SetValue("")
+	m.loadingFiles = true
+	return func() tea.Msg {
+		files, err := manager.Files(p)
+		return filesMsg{key: key, files: files, err: err}
+	}
+}
+
+func (m Model) handleFiles(msg filesMsg) Model {
+	if msg.key != m.filesFor {
+		return m
+	}
+	m.loadingFiles = false
+	m.files = msg.files
+	if msg.err != nil {
+		m.filesErr = msg.err.Error()
+	}
+	m.rebuildFileTree()
+	return m
+}
+
+// rebuildFileTree refilters the file list into m.filesTree. It runs when the
+// list or the filter changes, not on every keypress or redraw.
+func (m *Model) rebuildFileTree() {
+	m.filesTree = nil
+	if m.files != nil {
+		m.filesTree = buildFileTree(m.files, m.filesInput.Value())
+	}
+}
+
+func newFilesInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "Filter: "
+	ti.Placeholder = "part of a path"
+	ti.CharLimit = 256
+	ti.Cursor.Style = lipgloss.NewStyle().Foreground(CurrentTheme.Focus)
+	ti.TextStyle = lipgloss.NewStyle().Foreground(CurrentTheme.Focus)
+	return ti
+}
+
+// buildFileTree arranges paths into a tree under "/", keeping only the files
+// and directories whose path contains query.
+func buildFileTree(paths []string, query string) *fileNode {
+	root := &fileNode{name: "/", path: "/", dir: true}
+	index := map[string]*fileNode{"/": root}
+	query = strings.ToLower(query)
+
+	var add func(path string, dir bool) *fileNode
+	add = func(path string, dir bool) *fileNode {
+		if n, ok := index[path]; ok {
+			return n
+		}
+		trimmed := strings.TrimSuffix(path, "/")
+		i := strings.LastIndex(trimmed, "/")
+		parent := add(trimmed[:i+1], true)
+		n := &fileNode{name: trimmed[i+1:], path: path, dir: dir}
+		if dir {
+			n.name += "/"
+		}
+		parent.children = append(parent.children, n)
+		index[path] = n
+		return n
+	}
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") || !strings.Contains(strings.ToLower(path), query) {
+			continue
+		}
+		add(path, strings.HasSuffix(path, "/"))
+	}
+
+	var finish func(n *fileNode) int
+	finish = func(n *fileNode) int {
+		if !n.dir {
+			return 1
+		}
+		slices.SortFunc(n.children, func(a, b *fileNode) int {
+			if a.dir != b.dir {
+				if a.dir {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a.name, b.name)
+		})
+		n.count = 0
+		for _, c := range n.children {
+			n.count += finish(c)
+		}
+		return n.count
+	}
+	finish(root)
+	return root
+}
+
+// fileRows flattens the expanded part of the tree. Without a filter only
+// the root and chains of lone directories (/ → usr/) start open; with one,
+// everything that matched is shown.
+func (m Model) fileRows() []fileRow {
+	if m.filesTree == nil {
+		return nil
+	}
+	filtering := m.filesInput.Value() != ""
+	var rows []fileRow
+	var walk func(n *fileNode, prefix string, depth int, last, only bool)
+	walk = func(n *fileNode, prefix string, depth int, last, only bool) {
+		rows = append(rows, fileRow{node: n, depth: depth, prefix: prefix, last: last})
+		open, ok := m.filesExpanded[n.path]
+		if !ok {
+			open = filtering || depth == 0 || only
+		}
+		if !n.dir || !open {
+			return
+		}
+		childPrefix := prefix
+		if depth > 0 {
+			if last {
+				childPrefix += "   "
+			} else {
+				childPrefix += "│  "
+			}
+		}
+		for i, c := range n.children {
+			walk(c, childPrefix, depth+1, i == len(n.children)-1, len(n.children) == 1)
+		}
+	}
+	walk(m.filesTree, "", 0, true, false)
+	return rows
+}
+
+// rowExpanded reports whether the directory at rows[i] is open, which is
+// when its first child is the next row.
+func rowExpanded(rows []fileRow, i int) bool {
+	return i+1 < len(rows) && rows[i+1].depth > rows[i].depth
+}
+
+// updateFiles handles keys while the file list has focus in the detail panel.
+func (m Model) updateFiles(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.filesSearching {
+		switch msg.String() {
+		case "enter":
+			m.filesSearching = false
+			m.filesInput.Blur()
+		case "esc":
+			m.filesSearching = false
+			m.filesInput.Blur()
+			m.filesInput.SetValue("")
+			m.filesCursor = 0
+			m.rebuildFileTree()
+		default:
+			query := m.filesInput.Value()
+			var cmd tea.Cmd
+			m.filesInput, cmd = m.filesInput.Update(msg)
+			if m.filesInput.Value() != query {
+				m.filesCursor = 0
+				m.rebuildFileTree()
+			}
+			m.viewport.SetContent(m.renderFiles(m.viewport.Width))
+			m.viewport.GotoTop()
+			return m, cmd
+		}
+		m.viewport.SetContent(m.renderFiles(m.viewport.Width))
+		return m, nil
+	}
+
+	rows := m.fileRows()
+	switch msg.String() {
+	case "up", "k":
+		if m.filesCursor > 0 {
+			m.filesCursor--
+		}
+	case "down", "j":
+		if m.filesCursor < len(rows)-1 {
+			m.filesCursor++
+		}
+	case " ", "right", "l", "left", "h":
+		if m.filesCursor >= len(rows) || !rows[m.filesCursor].node.dir {
+			return m, nil
+		}
+		row := rows[m.filesCursor]
+		open := rowExpanded(rows, m.filesCursor)
+		switch msg.String() {
+		case "right", "l":
+			open = true
+		case "left", "h":
+			open = false
+		default:
+			open = !open
+		}
+		m.filesExpanded[row.node.path] = open
+	case "e":
+		for _, path := range m.files {
+			if strings.HasSuffix(path, "/") {
+				m.filesExpanded[path] = true
+			}
+		}
+	case "/":
+		m.filesSearching = true
+		return m, m.filesInput.Focus()
+	case "esc":
+		if m.filesInput.Value() != "" {
+			m.filesInput.SetValue("")
+			m.filesCursor = 0
+			m.rebuildFileTree()
+			break
+		}
+		m.showingFiles = false
+		return m, nil
+	default:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	m.viewport.SetContent(m.renderFiles(m.viewport.Width))
+	line := lipgloss.Height(m.filesHeader(m.viewport.Width)) - 1 + m.filesCursor
+	if line < m.viewport.YOffset {
+		m.viewport.SetYOffset(line)
+	} else if line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height + 1)
+	}
+	return m, nil
+}
+
+// filesHeader is everything above the rows. It ends with a newline.
+func (m Model) filesHeader(width int) string {
+	var sb strings.Builder
+	headerStyle := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Background(CurrentTheme.Highlight).Padding(0, 1)
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+
+	fmt.Fprintf(&sb, "\n%s\n\n", headerStyle.Render("Files of "+m.filesFor))
+
+	switch {
+	case m.loadingFiles:
+		sb.WriteString("Reading file list...\n")
+	case m.filesErr != "":
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Render("Error: "+m.filesErr) + "\n")
+	default:
+		files, dirs := 0, 0
+		for _, path := range m.files {
+			if strings.HasSuffix(path, "/") {
+				dirs++
+			} else {
+				files++
+			}
+		}
+		sb.WriteString(gray.Render(fmt.Sprintf("%d files in %d directories", files, dirs)) + "\n")
+		if m.filesSearching || m.filesInput.Value() != "" {
+			sb.WriteString(m.filesInput.View() + "\n")
+		}
+		sb.WriteByte('\n')
+	}
+	return lipgloss.NewStyle().Width(width).Render(sb.String())
+}
+
+func (m Model) renderFiles(width int) string {
+	var sb strings.Builder
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+
+	sb.WriteString(m.filesHeader(width))
+	if m.files == nil {
+		return sb.String()
+	}
+
+	rows := m.fileRows()
+	if len(rows) == 1 && m.filesInput.Value() != "" {
+		sb.WriteString(gray.Render("No matching files") + "\n")
+	}
+	for i, row := range rows {
+		n := row.node
+		guide := ""
+		if row.depth > 0 {
+			guide = row.prefix + "├─ "
+			if row.last {
+				guide = row.prefix + "└─ "
+			}
+		}
+
+		toggle := "  "
+		if n.dir && len(n.children) > 0 {
+			toggle = "▸ "
+			if rowExpanded(rows, i) {
+				toggle = "▾ "
+			}
+		}
+
+		label := n.name
+		style := lipgloss.NewStyle().Foreground(CurrentTheme.Text)
+		if n.dir {
+			style = lipgloss.NewStyle().Foreground(CurrentTheme.Blue).Bold(true)
+		}
+		if i == m.filesCursor {
+			style = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true)
+		}
+
+		note := ""
+		if n.dir {
+			note = " " + gray.Render(fmt.Sprintf("(%d)", n.count))
+		}
+
+		line := fmt.Sprintf("%s%s%s%s", gray.Render(guide), gray.Render(toggle), style.Render(label), note)
+		sb.WriteString(lipgloss.NewStyle().MaxWidth(width).Render(line))
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteString(gray.Render("\n[ ↑/↓: Move • Space/←/→: Collapse/Expand • e: Expand All • /: Filter • Esc: Back ]"))
+	return lipgloss.NewStyle().Width(width).Render(sb.String())
+}
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type graphExportedMsg struct {
+	path string
+	err  error
+	// aurErr is why AUR dependencies were left out of a written graph.
+	aurErr error
+}
+
+// graphExtensions are the file extensions for manager.GraphFormats.
+var graphExtensions = map[string]string{"dot": ".dot", "mermaid": ".mmd"}
+
+// exportGraph writes p's dependency graph to the current directory, e.g.
+// gopac-firefox.dot. When p's tree is open the graph follows its direction and
+// goes as deep as the tree is expanded.
+func (m Model) exportGraph(p manager.Package, format string) tea.Cmd {
+	opts := manager.GraphOptions{}
+	path := "gopac-" + p.Name
+	if m.showingTree && m.depTreeFor == p.QualifiedName() {
+		opts.Reverse = m.treeReverse
+		if m.depTree != nil {
+			opts.Depth = 1
+			for _, row := range m.treeRows() {
+				opts.Depth = max(opts.Depth, row.depth)
+			}
+		}
+	}
+	if opts.Reverse {
+		path += "-reverse"
+	}
+	path += graphExtensions[format]
+
+	return func() tea.Msg {
+		g, err := manager.BuildGraph(context.Background(), []string{p.Name}, opts)
+		if err != nil {
+			return graphExportedMsg{err: err}
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return graphExportedMsg{err: err}
+		}
+		err = manager.WriteGraph(f, g, format)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return graphExportedMsg{path: path, err: err, aurErr: g.AURErr}
+	}
+}
+
+func (m Model) handleGraphExported(msg graphExportedMsg) Model {
+	if msg.err != nil {
+		m.notice = ""
+		m.pushError("graph", "Graph export", msg.err, "", nil)
+		return m
+	}
+	m.notice = "Graph written to " + msg.path
+	if msg.aurErr != nil {
+		m.pushError("graph", "Graph export", msg.aurErr, "AUR dependencies show as missing", nil)
+	}
+	return m
+}
@@ -0,0 +1,158 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"gopac/internal/manager"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type historyMsg struct {
+	txs []manager.HistoryTransaction
+	err error
+}
+
+// historyItem is one package change in the HISTORY tab, carrying the whole
+// transaction it belongs to for the detail panel.
+type historyItem struct {
+	Tx    manager.HistoryTransaction
+	Entry manager.HistoryEntry
+}
+
+func actionColor(action string) lipgloss.Color {
+	switch action {
+	case "installed":
+		return CurrentTheme.Green
+	case "removed":
+		return CurrentTheme.Red
+	case "downgraded":
+		return CurrentTheme.Orange
+	case "reinstalled":
+		return CurrentTheme.Blue
+	default:
+		return CurrentTheme.Yellow
+	}
+}
+
+func actionIcon(action string) string {
+	switch action {
+	case "installed":
+		return "+"
+	case "removed":
+		return "-"
+	case "downgraded":
+		return "↓"
+	case "reinstalled":
+		return "↻"
+	default:
+		return "↑"
+	}
+}
+
+func versionChange(e manager.HistoryEntry) string {
+	switch {
+	case e.OldVersion != "" && e.NewVersion != "":
+		return e.OldVersion + " → " + e.NewVersion
+	case e.OldVersion != "":
+		return e.OldVersion
+	default:
+		return e.NewVersion
+	}
+}
+
+func (h historyItem) Title() string {
+	color := actionColor(h.Entry.Action)
+	return fmt.Sprintf("%s %s",
+		lipgloss.NewStyle().Foreground(color).Render(actionIcon(h.Entry.Action)),
+		lipgloss.NewStyle().Foreground(CurrentTheme.Text).Bold(true).Render(h.Entry.Package),
+	)
+}
+
+func (h historyItem) Description() string {
+	return fmt.Sprintf("%s | %s %s",
+		h.Entry.Time.Local().Format("2006-01-02 15:04"),
+		lipgloss.NewStyle().Foreground(actionColor(h.Entry.Action)).Render(h.Entry.Action),
+		versionChange(h.Entry),
+	)
+}
+
+func (h historyItem) FilterValue() string { return h.Entry.Package }
+
+func (m *Model) loadHistory() tea.Cmd {
+	if m.loadingHistory {
+		return nil
+	}
+	m.loadingHistory = true
+	return func() tea.Msg {
+		txs, err := manager.History()
+		return historyMsg{txs: txs, err: err}
+	}
+}
+
+// handleHistory shows the parsed log, or says why it couldn't be read.
+func (m Model) handleHistory(msg historyMsg) Model {
+	m.loadingHistory = false
+	if msg.err != nil {
+		m.pushError("history", "Reading pacman.log", msg.err, "", func(m *Model) tea.Cmd {
+			return m.loadHistory()
+		})
+	} else {
+		m.dropToasts("history")
+		m.historyLoaded = true
+		m.history = msg.txs
+	}
+	m.updateListItems()
+	return m
+}
+
+// historyItems lists the entries matching the search input, newest first.
+func (m *Model) historyItems() []list.Item {
+	filter, err := manager.ParseHistoryFilter(m.currentQuery)
+	if err != nil {
+		m.queryErr = err.Error()
+	}
+
+	var items []list.Item
+	for t := len(m.history) - 1; t >= 0; t-- {
+		tx := m.history[t]
+		for e := len(tx.Entries) - 1; e >= 0; e-- {
+			if filter.Matches(tx.Entries[e]) {
+				items = append(items, historyItem{Tx: tx, Entry: tx.Entries[e]})
+			}
+		}
+	}
+	return items
+}
+
+func renderTransaction(h historyItem, width int) string {
+	var sb strings.Builder
+	keyStyle := LabelStyle.Width(16)
+	headerStyle := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Background(CurrentTheme.Highlight).Padding(0, 1)
+
+	fmt.Fprintf(&sb, "\n%s\n\n", headerStyle.Render("Transaction"))
+	fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render("Started"), ValueStyle.Render(h.Tx.Start.Local().Format("Mon 02 Jan 2006 03:04:05 PM MST")))
+	if h.Tx.Command != "" {
+		fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render("Command"), ValueStyle.Render(h.Tx.Command))
+	}
+	fmt.Fprintf(&sb, "%s : %s\n\n", keyStyle.Render("Packages"), ValueStyle.Render(fmt.Sprint(len(h.Tx.Entries))))
+
+	for _, e := range h.Tx.Entries {
+		name := lipgloss.NewStyle().Foreground(CurrentTheme.Text).Render(e.Package)
+		if e == h.Entry {
+			name = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true).Render(e.Package)
+		}
+		fmt.Fprintf(&sb, "%s %s %s %s\n",
+			lipgloss.NewStyle().Foreground(actionColor(e.Action)).Render(actionIcon(e.Action)),
+			lipgloss.NewStyle().Foreground(actionColor(e.Action)).Render(fmt.Sprintf("%-11s", e.Action)),
+			name,
+			lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render(versionChange(e)),
+		)
+	}
+
+	sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("\n[ Enter: Open package details ]"))
+	return lipgloss.NewStyle().Width(width).Render(sb.String())
+}
@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopac/internal/manager"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// inventoryFilters and inventorySorts are cycled with f and s on the
+// INSTALLED tab.
+var (
+	inventoryFilters = []string{"all", "explicit", "dependencies", "native", "foreign"}
+	inventorySorts   = []string{"name", "size", "install date"}
+)
+
+func inventoryItems(pkgs []manager.Package) []Item {
+	items := make([]Item, len(pkgs))
+	for i, p := range pkgs {
+		items[i] = Item{Pkg: p, ShowSize: true}
+	}
+	return items
+}
+
+type inventoryMsg struct {
+	pkgs []manager.Package
+	err  error
+}
+
+// loadInventory reads the installed packages for the INSTALLED tab. It is
+// the only place the full inventory is loaded, off the UI's main loop.
+func (m *Model) loadInventory() tea.Cmd {
+	m.loadingInventory = true
+	return func() tea.Msg {
+		pkgs, err := manager.LoadInstalledPackages()
+		return inventoryMsg{pkgs: pkgs, err: err}
+	}
+}
+
+func (m Model) handleInventory(msg inventoryMsg) Model {
+	m.loadingInventory = false
+	if msg.err != nil {
+		m.pushError("inventory", "Reading installed packages", msg.err, "", func(m *Model) tea.Cmd {
+			return m.loadInventory()
+		})
+		return m
+	}
+	m.dropToasts("inventory")
+	m.inventory = inventoryItems(msg.pkgs)
+	m.updateListItems()
+	return m
+}
+
+func inventoryMatches(p manager.Package, filter string) bool {
+	switch filter {
+	case "explicit":
+		return !p.Dependency
+	case "dependencies":
+		return p.Dependency
+	case "native":
+		return !p.Foreign
+	case "foreign":
+		return p.Foreign
+	default:
+		return true
+	}
+}
+
+// localMatches reports whether p's name contains the lowercase query, or
+// its description too when D turned description search on.
+func (m Model) localMatches(p manager.Package, query string) bool {
+	return strings.Contains(strings.ToLower(p.Name), query) ||
+		m.searchDesc && strings.Contains(strings.ToLower(p.Description), query)
+}
+
+// inventoryListItems filters the installed packages with the search query
+// and by the current category, in the current sort order.
+func (m *Model) inventoryListItems(q manager.PackageQuery) []list.Item {
+	query := strings.ToLower(q.Text)
+	filter := inventoryFilters[m.inventoryFilter]
+
+	var items []Item
+	for i := range m.inventory {
+		it := &m.inventory[i]
+		_, it.MarkedRem = m.markedRemove[it.Pkg.Name]
+		it.Query = q.Text
+		if inventoryMatches(it.Pkg, filter) && q.Matches(it.Pkg) && m.localMatches(it.Pkg, query) {
+			items = append(items, *it)
+		}
+	}
+
+	switch inventorySorts[m.inventorySort] {
+	case "size":
+		slices.SortStableFunc(items, func(a, b Item) int {
+			return cmp.Compare(b.Pkg.InstalledBytes, a.Pkg.InstalledBytes)
+		})
+	case "install date":
+		slices.SortStableFunc(items, func(a, b Item) int {
+			return cmp.Compare(b.Pkg.InstallDate, a.Pkg.InstallDate)
+		})
+	}
+
+	listItems := make([]list.Item, len(items))
+	for i, it := range items {
+		listItems[i] = it
+	}
+	return listItems
+}
+
+func (m Model) inventorySummary() string {
+	return fmt.Sprintf("%d %s, by %s", len(m.list.Items()),
+		inventoryFilters[m.inventoryFilter], inventorySorts[m.inventorySort])
+}
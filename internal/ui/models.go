@@ -2,7 +2,10 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,13 +19,31 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-var tabs = []string{"ALL", "AUR", "OFFICIAL", "INSTALLED"}
+var baseTabs = []string{"ALL", "AUR", "OFFICIAL", "INSTALLED", "UPDATES", "ORPHANS", "HISTORY"}
+
+// buildTabs appends one tab per enabled sync repository to the fixed tabs.
+// Repository tabs hold the repo name as-is; the view upper-cases them.
+func buildTabs() []string {
+	tabs := append([]string{}, baseTabs...)
+	repos, err := manager.Repositories()
+	if err != nil {
+		return tabs
+	}
+	for _, repo := range repos {
+		if !slices.Contains(tabs, repo) {
+			tabs = append(tabs, repo)
+		}
+	}
+	return tabs
+}
 
 type Item struct {
 	Pkg        manager.Package
 	Query      string
 	MarkedInst bool
 	MarkedRem  bool
+	Held       bool
+	ShowSize   bool
 }
 
 func (i Item) Title() string {
@@ -39,84 +60,183 @@ func (i Item) Title() string {
 	} else if i.MarkedRem {
 		icon = ""
 		iconColor = CurrentTheme.Red
+	} else if i.Held {
+		icon = "✗"
+		iconColor = CurrentTheme.Gray
 	} else if i.Pkg.IsInstalled {
 		icon = "✓"
 	}
 
-	name := i.Pkg.Name
-	var titleSB strings.Builder
+	return fmt.Sprintf("%s %s",
+		lipgloss.NewStyle().Foreground(iconColor).Render(icon),
+		highlightMatches(i.Pkg.Name, i.Query, baseColor),
+	)
+}
 
-	if i.Query != "" && strings.Contains(strings.ToLower(name), strings.ToLower(i.Query)) {
-		lowerName := strings.ToLower(name)
-		lowerQuery := strings.ToLower(i.Query)
-		idx := strings.Index(lowerName, lowerQuery)
+// highlightMatches renders name with the characters matching query, as
+// found by manager.MatchPositions, highlighted.
+func highlightMatches(name, query string, color lipgloss.Color) string {
+	plain := lipgloss.NewStyle().Foreground(color).Bold(true)
+	match := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true).Underline(true)
 
-		if idx >= 0 {
-			titleSB.WriteString(lipgloss.NewStyle().Foreground(baseColor).Bold(true).Render(name[:idx]))
-			titleSB.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true).Underline(true).Render(name[idx : idx+len(lowerQuery)]))
-			titleSB.WriteString(lipgloss.NewStyle().Foreground(baseColor).Bold(true).Render(name[idx+len(lowerQuery):]))
-		} else {
-			titleSB.WriteString(lipgloss.NewStyle().Foreground(baseColor).Bold(true).Render(name))
-		}
-	} else {
-		titleSB.WriteString(lipgloss.NewStyle().Foreground(baseColor).Bold(true).Render(name))
+	matched := make(map[int]bool)
+	for _, pos := range manager.MatchPositions(name, query) {
+		matched[pos] = true
+	}
+	if len(matched) == 0 {
+		return plain.Render(name)
 	}
 
-	return fmt.Sprintf("%s %s",
-		lipgloss.NewStyle().Foreground(iconColor).Render(icon),
-		titleSB.String(),
-	)
+	// Render runs of matched and unmatched runes together.
+	var sb strings.Builder
+	runes := []rune(name)
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && matched[end] == matched[start] {
+			end++
+		}
+		style := plain
+		if matched[start] {
+			style = match
+		}
+		sb.WriteString(style.Render(string(runes[start:end])))
+		start = end
+	}
+	return sb.String()
 }
 
 func (i Item) Description() string {
-	aurTag := lipgloss.NewStyle().Foreground(CurrentTheme.RepoAUR).Render("AUR")
-	offTag := lipgloss.NewStyle().Foreground(CurrentTheme.RepoOfficial).Render("Official")
-	tag := offTag
+	tag := lipgloss.NewStyle().Foreground(CurrentTheme.RepoOfficial).Render(repoLabel(i.Pkg))
 	if i.Pkg.IsAUR {
-		tag = aurTag
+		tag = lipgloss.NewStyle().Foreground(CurrentTheme.RepoAUR).Render(repoLabel(i.Pkg))
+	}
+	desc := fmt.Sprintf("%s | %s", tag, versionLabel(i.Pkg))
+	if i.ShowSize && i.Pkg.InstalledSize != "" {
+		desc += " | " + i.Pkg.InstalledSize
+	}
+	if updateAvailable(i.Pkg) {
+		desc += " " + lipgloss.NewStyle().Foreground(CurrentTheme.Green).Render("⬆")
 	}
-	return fmt.Sprintf("%s | %s", tag, i.Pkg.Version)
+	return desc
 }
 
 func (i Item) FilterValue() string { return i.Pkg.Name }
 
+func repoLabel(p manager.Package) string {
+	switch {
+	case p.IsAUR:
+		return "AUR"
+	case p.Foreign:
+		return "foreign"
+	case p.Repository != "":
+		return p.Repository
+	default:
+		return "Official"
+	}
+}
+
 type (
-	InstalledMapMsg  map[string]bool
+	InstalledMapMsg  map[string]string
 	PackageDetailMsg manager.Package
 	TickMsg          time.Time
 	bulkDoneMsg      struct{}
 )
 
+// searchResultsMsg is what one source found for search number seq.
 type searchResultsMsg struct {
-	query string
-	pkgs  []manager.Package
-	err   error
+	seq    int
+	query  string
+	source manager.SearchSource
+	pkgs   []manager.Package
+	err    error
 }
 
 type Model struct {
-	list            list.Model
-	input           textinput.Model
-	viewport        viewport.Model
-	spinner         spinner.Model
-	searching       bool
-	isSearching     bool
-	allItems        []Item
-	activeTab       int
-	width, height   int
-	listWidth       int
-	descWidth       int
-	panelHeight     int
-	currentQuery    string
-	lastSelectedPkg string
-	showingPKGBUILD bool
-	showingHelp     bool
-	focusSide       int // 0: List, 1: Detail, 2: Search
-	searchCancel    context.CancelFunc
-	searchHistory   []string
-	historyIdx      int
-	markedInstall   map[string]manager.Package
-	markedRemove    map[string]manager.Package
+	list              list.Model
+	input             textinput.Model
+	viewport          viewport.Model
+	spinner           spinner.Model
+	searching         bool
+	isSearching       bool
+	allItems          []Item
+	tabs              []string
+	activeTab         int
+	updateItems       []Item
+	updatesLoaded     bool
+	heldBack          map[string]bool
+	inventory         []Item
+	inventoryFilter   int
+	inventorySort     int
+	orphanItems       []Item
+	showingTree       bool
+	depTree           *manager.DepNode
+	depTreeFor        string
+	treeReverse       bool
+	loadingTree       bool
+	treeErr           string
+	treeCursor        int
+	treeExpanded      map[string]bool
+	showingFiles      bool
+	filesFor          string
+	files             []string
+	filesTree         *fileNode // files filtered by filesInput, rebuilt when either changes
+	loadingFiles      bool
+	filesErr          string
+	filesCursor       int
+	filesExpanded     map[string]bool
+	filesSearching    bool
+	filesInput        textinput.Model
+	loadingInventory  bool
+	orphansLoaded     bool
+	loadingOrphans    bool
+	orphansOptional   bool
+	pendingUpgrade    *exec.Cmd
+	news              []manager.NewsItem
+	newsSince         time.Time // the last upgrade, zero when unknown
+	newsErr           error     // why the feed couldn't be checked
+	showingNews       bool
+	checkingNews      bool
+	downgradePkg      manager.Package
+	downgradeVersions []manager.CachedPackage
+	downgradeIdx      int
+	downgradeErr      string
+	showingDowngrade  bool
+	loadingDowngrade  bool
+	history           []manager.HistoryTransaction
+	historyLoaded     bool
+	loadingHistory    bool
+	searchedQuery     string
+	jumpTo            string
+	queryErr          string
+	searchDesc        bool // search descriptions as well as names
+	// Each search gets a new searchSeq; the sources answer separately and
+	// their results are merged into allItems as they arrive.
+	searchSeq         int
+	resultsSeq        int // the search sourcePkgs belong to
+	sourcePkgs        map[manager.SearchSource][]manager.Package
+	sourceLoading     map[manager.SearchSource]bool
+	sourceErrs        map[manager.SearchSource]error
+	notice            string
+	toasts            []toast
+	errorLog          []toast
+	checkingUpdates   bool
+	updatesWarn       *manager.UpdatesWarning // what the last check couldn't cover
+	width, height     int
+	listWidth         int
+	descWidth         int
+	panelHeight       int
+	currentQuery      string
+	lastSelectedPkg   string
+	showingPKGBUILD   bool
+	showingHelp       bool
+	focusSide         int // 0: List, 1: Detail, 2: Search
+	searchCancel      context.CancelFunc
+	searchHistory     []string
+	historyIdx        int
+	markedInstall     map[string]manager.Package
+	markedRemove      map[string]manager.Package
 	loadingDetailsFor string
+	detailsErrs       map[string]error // by qualified name, until retried
 }
 
 func NewModel() Model {
@@ -143,13 +263,18 @@ func NewModel() Model {
 	l.SetFilteringEnabled(false)
 
 	ti.Focus()
-	return Model{
-		list: l, input: ti, viewport: viewport.New(0, 0), spinner: s, searching: true, allItems: []Item{}, activeTab: 0, focusSide: 2,
+	m := Model{
+		list: l, input: ti, viewport: viewport.New(0, 0), spinner: s, searching: true, allItems: []Item{}, tabs: buildTabs(), activeTab: 0, focusSide: 2,
 		searchHistory: []string{}, historyIdx: -1,
-		markedInstall: make(map[string]manager.Package),
-		markedRemove:  make(map[string]manager.Package),
+		markedInstall:     make(map[string]manager.Package),
+		markedRemove:      make(map[string]manager.Package),
+		heldBack:          make(map[string]bool),
+		filesInput:        newFilesInput(),
 		loadingDetailsFor: "",
+		detailsErrs:       make(map[string]error),
+		loadingInventory:  true,
 	}
+	return m
 }
 
 func tickCmd() tea.Cmd {
@@ -158,7 +283,35 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-func (m Model) Init() tea.Cmd { return tea.Batch(textinput.Blink, tickCmd(), m.spinner.Tick) }
+// WithQuery starts the model with query already searched, e.g. the
+// candidates for a missing command.
+func (m Model) WithQuery(query string) Model {
+	m.input.SetValue(query)
+	m.input.Blur()
+	m.searching = false
+	m.focusSide = 0
+	m.currentQuery = query
+	m.searchedQuery = query
+	m.startSearch()
+	m.searchHistory = append(m.searchHistory, query)
+	m.historyIdx = len(m.searchHistory)
+	return m
+}
+
+// WithDescriptionSearch sets whether searches start out matching
+// descriptions as well as names.
+func (m Model) WithDescriptionSearch(on bool) Model {
+	m.searchDesc = on
+	return m
+}
+
+func (m Model) Init() tea.Cmd {
+	cmds := []tea.Cmd{textinput.Blink, tickCmd(), m.spinner.Tick, m.loadInventory()}
+	if m.currentQuery != "" {
+		cmds = append(cmds, performSearch(context.Background(), m.searchSeq, m.currentQuery, m.searchDesc))
+	}
+	return tea.Batch(cmds...)
+}
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
@@ -194,8 +347,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Check if click was in the search bar area or tabs area
 				// Simple approximation: tabs are on the right
 				if msg.X > m.width-20 {
-					m.activeTab = (m.activeTab + 1) % len(tabs)
-					m.updateListItems()
+					cmds = append(cmds, m.setTab(m.activeTab+1))
 				} else if msg.X > m.listWidth && msg.X < m.width-20 {
 					m.focusSide = 2
 					m.searching = true
@@ -223,6 +375,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
 		}
+		m.notice = ""
+
+		if m.showingNews {
+			return m.updateNews(msg)
+		}
+		if m.showingDowngrade {
+			return m.updateDowngrade(msg)
+		}
+		// The file filter takes typing, including keys that are global
+		// shortcuts elsewhere.
+		if m.showingFiles && m.focusSide == 1 && (m.filesSearching || msg.String() == "/") {
+			return m.updateFiles(msg)
+		}
 
 		// Cycle Focus: List(0) -> Detail(1) -> Search(2)
 		if msg.String() == "tab" {
@@ -258,18 +423,6 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.focusSide = 0 // Auto focus list
 				m.currentQuery = m.input.Value()
 
-				if m.searchCancel != nil {
-					m.searchCancel()
-					m.searchCancel = nil
-				}
-
-				if m.currentQuery == "" {
-					m.allItems = []Item{}
-					m.updateListItems()
-					m.isSearching = false
-					return m, nil
-				}
-
 				if m.input.Value() != "" {
 					// Add to history if not same as last
 					if len(m.searchHistory) == 0 || m.searchHistory[len(m.searchHistory)-1] != m.input.Value() {
@@ -278,10 +431,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.historyIdx = len(m.searchHistory)
 				}
 
-				ctx, cancel := context.WithCancel(context.Background())
-				m.searchCancel = cancel
-				m.isSearching = true
-				return m, performSearch(ctx, m.input.Value())
+				return m, m.runSearch()
 			}
 			if msg.String() == "esc" {
 				m.searching = false
@@ -329,8 +479,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "U":
-			c := manager.UpdateSystem()
-			return m, tea.ExecProcess(c, func(err error) tea.Msg { return refreshInstalledStatus() })
+			var c *exec.Cmd
+			if m.tabs[m.activeTab] == "UPDATES" {
+				c = m.selectiveUpdateCmd()
+			} else {
+				c = manager.UpdateSystem()
+			}
+			if c == nil {
+				m.notice = m.nothingToUpgrade()
+				return m, nil
+			}
+			return m, m.checkNewsBefore(c)
 
 		case "I":
 			if len(m.markedInstall) > 0 || len(m.markedRemove) > 0 {
@@ -338,11 +497,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				var toInstallAUR []string
 				var toRemove []string
 
-				for name, pkg := range m.markedInstall {
+				for _, pkg := range m.markedInstall {
 					if pkg.IsAUR {
-						toInstallAUR = append(toInstallAUR, name)
+						toInstallAUR = append(toInstallAUR, pkg.Name)
 					} else {
-						toInstallOfficial = append(toInstallOfficial, name)
+						toInstallOfficial = append(toInstallOfficial, pkg.QualifiedName())
 					}
 				}
 				for name := range m.markedRemove {
@@ -351,11 +510,102 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				c := manager.BulkActionCmd(toInstallOfficial, toInstallAUR, toRemove)
 				if c != nil {
-					return m, tea.ExecProcess(c, func(err error) tea.Msg { return bulkDoneMsg{} })
+					return m, m.execTransaction(c, func(err error) tea.Msg { return bulkDoneMsg{} })
 				}
 			}
 			return m, nil
 
+		case "t", "r":
+			reverse := msg.String() == "r"
+			if m.showingTree && m.treeReverse == reverse {
+				m.showingTree = false
+				return m, nil
+			}
+			if i, ok := m.list.SelectedItem().(Item); ok && (!reverse || i.Pkg.IsInstalled) {
+				m.focusSide = 1
+				m.searching = false
+				m.input.Blur()
+				cmd := m.openDepTree(i.Pkg, reverse)
+				m.viewport.SetContent(m.renderDepTree(m.viewport.Width))
+				m.viewport.GotoTop()
+				return m, cmd
+			}
+
+		case "F":
+			if m.showingFiles {
+				m.showingFiles = false
+				return m, nil
+			}
+			if i, ok := m.list.SelectedItem().(Item); ok {
+				m.focusSide = 1
+				m.searching = false
+				m.input.Blur()
+				cmd := m.openFiles(i.Pkg)
+				m.viewport.SetContent(m.renderFiles(m.viewport.Width))
+				m.viewport.GotoTop()
+				return m, cmd
+			}
+
+		case "x", "X":
+			if i, ok := m.list.SelectedItem().(Item); ok {
+				format := "dot"
+				if msg.String() == "X" {
+					format = "mermaid"
+				}
+				return m, m.exportGraph(i.Pkg, format)
+			}
+			return m, nil
+
+		case "d":
+			if i, ok := m.list.SelectedItem().(Item); ok && i.Pkg.IsInstalled {
+				return m, m.openDowngrade(i.Pkg)
+			}
+			return m, nil
+
+		case "o":
+			if m.tabs[m.activeTab] == "ORPHANS" {
+				m.orphansOptional = !m.orphansOptional
+				return m, m.loadOrphans()
+			}
+
+		case "f":
+			if m.tabs[m.activeTab] == "INSTALLED" {
+				m.inventoryFilter = (m.inventoryFilter + 1) % len(inventoryFilters)
+				m.updateListItems()
+				return m, nil
+			}
+
+		case "s":
+			if m.tabs[m.activeTab] == "INSTALLED" {
+				m.inventorySort = (m.inventorySort + 1) % len(inventorySorts)
+				m.updateListItems()
+				return m, nil
+			}
+
+		case "A":
+			if m.tabs[m.activeTab] == "ORPHANS" {
+				m.queueOrphans()
+				return m, nil
+			}
+
+		case "D":
+			if m.tabs[m.activeTab] == "HISTORY" {
+				return m, nil
+			}
+			m.searchDesc = !m.searchDesc
+			m.notice = "Searching names only"
+			if m.searchDesc {
+				m.notice = "Searching names and descriptions"
+			}
+			if m.isLocalTab() {
+				m.updateListItems()
+				return m, nil
+			}
+			return m, m.runSearch()
+
+		case "R":
+			return m, m.retryToast()
+
 		case "C":
 			m.markedInstall = make(map[string]manager.Package)
 			m.markedRemove = make(map[string]manager.Package)
@@ -365,6 +615,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "p":
 			if i, ok := m.list.SelectedItem().(Item); ok && i.Pkg.IsAUR {
 				m.showingPKGBUILD = !m.showingPKGBUILD
+				m.showingTree = false
+				m.showingFiles = false
 				var fetchCmd tea.Cmd
 				if m.showingPKGBUILD && i.Pkg.PKGBUILD == "" {
 					fetchCmd = fetchPKGBUILD(i.Pkg)
@@ -372,7 +624,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.showingPKGBUILD {
 					m.viewport.SetContent(renderPKGBUILD(i.Pkg, m.viewport.Width))
 				} else {
-					m.viewport.SetContent(renderDescription(i.Pkg, m.viewport.Width))
+					m.viewport.SetContent(renderDescription(i.Pkg, i.Query, m.viewport.Width))
 				}
 				return m, fetchCmd
 			}
@@ -382,26 +634,37 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case 0:
 			switch msg.String() {
 			case "left", "h":
-				m.activeTab = (m.activeTab - 1 + len(tabs)) % len(tabs)
-				m.updateListItems()
+				cmds = append(cmds, m.setTab(m.activeTab-1))
 			case "right", "l":
-				m.activeTab = (m.activeTab + 1) % len(tabs)
-				m.updateListItems()
+				cmds = append(cmds, m.setTab(m.activeTab+1))
 			case "enter":
+				if h, ok := m.list.SelectedItem().(historyItem); ok {
+					return m, m.jumpToPackage(h.Entry.Package)
+				}
 				if i, ok := m.list.SelectedItem().(Item); ok {
-					c := manager.InstallOrRemove(i.Pkg.Name, i.Pkg.IsAUR, i.Pkg.IsInstalled)
-					return m, tea.ExecProcess(c, func(err error) tea.Msg { return refreshInstalledStatus() })
+					name := i.Pkg.QualifiedName()
+					if i.Pkg.IsInstalled {
+						name = i.Pkg.Name
+					}
+					c := manager.InstallOrRemove(name, i.Pkg.IsAUR, i.Pkg.IsInstalled)
+					return m, m.execTransaction(c, func(err error) tea.Msg { return refreshInstalledStatus() })
 				}
 			case " ":
+				if i, ok := m.list.SelectedItem().(Item); ok && m.tabs[m.activeTab] == "UPDATES" {
+					m.heldBack[i.Pkg.Name] = !m.heldBack[i.Pkg.Name]
+					m.updateListItems()
+					return m, nil
+				}
 				if i, ok := m.list.SelectedItem().(Item); ok {
-					name := i.Pkg.Name
 					if i.Pkg.IsInstalled {
+						name := i.Pkg.Name
 						if _, exists := m.markedRemove[name]; exists {
 							delete(m.markedRemove, name)
 						} else {
 							m.markedRemove[name] = i.Pkg
 						}
 					} else {
+						name := i.Pkg.QualifiedName()
 						if _, exists := m.markedInstall[name]; exists {
 							delete(m.markedInstall, name)
 						} else {
@@ -415,66 +678,133 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.list, cmd = m.list.Update(msg)
 			cmds = append(cmds, cmd)
 		case 1:
+			if m.showingTree {
+				return m.updateDepTree(msg)
+			}
+			if m.showingFiles {
+				return m.updateFiles(msg)
+			}
 			m.viewport, cmd = m.viewport.Update(msg)
 			cmds = append(cmds, cmd)
 		}
 
 	case TickMsg:
 		cmds = append(cmds, tickCmd())
+		m.expireToasts(time.Time(msg))
 		if m.searching && m.input.Value() != m.currentQuery {
 			m.currentQuery = m.input.Value()
-			if m.searchCancel != nil {
-				m.searchCancel()
-				m.searchCancel = nil
-			}
-			if m.currentQuery == "" {
-				m.allItems = []Item{}
-				m.updateListItems()
-				m.isSearching = false
-			} else {
-				ctx, cancel := context.WithCancel(context.Background())
-				m.searchCancel = cancel
-				m.isSearching = true
-				cmds = append(cmds, performSearch(ctx, m.input.Value()))
-			}
+			cmds = append(cmds, m.runSearch())
 		}
 
 	case searchResultsMsg:
-		if msg.query != m.currentQuery {
+		if msg.seq != m.searchSeq {
 			// Outdated search result, ignore it!
 			return m, nil
 		}
-		m.isSearching = false
-		if msg.err == nil && msg.pkgs != nil {
-			items := make([]Item, len(msg.pkgs))
-			for i, pkg := range msg.pkgs {
-				items[i] = Item{Pkg: pkg, Query: msg.query}
-			}
-			m.allItems = items
-			m.updateListItems()
+		m.handleSearchResults(msg)
+		m.selectJumpTarget()
+		if !m.isSearching {
+			m.jumpTo = ""
 		}
 
 	case InstalledMapMsg:
 		for i := range m.allItems {
-			if _, ok := msg[m.allItems[i].Pkg.Name]; ok {
-				m.allItems[i].Pkg.IsInstalled = true
-			} else {
-				m.allItems[i].Pkg.IsInstalled = false
+			version, ok := msg[m.allItems[i].Pkg.Name]
+			m.allItems[i].Pkg.IsInstalled = ok
+			m.allItems[i].Pkg.InstalledVersion = version
+		}
+		// Whatever just ran may have changed what is outdated and added to
+		// the log.
+		m.updatesLoaded = false
+		m.orphansLoaded = false
+		m.historyLoaded = false
+		cmds = append(cmds, m.loadInventory())
+		switch m.tabs[m.activeTab] {
+		case "UPDATES":
+			cmds = append(cmds, m.loadUpdates())
+		case "ORPHANS":
+			cmds = append(cmds, m.loadOrphans())
+		}
+		m.updateListItems()
+
+	case historyMsg:
+		m = m.handleHistory(msg)
+
+	case newsMsg:
+		return m.handleNews(msg)
+
+	case inventoryMsg:
+		m = m.handleInventory(msg)
+
+	case orphansMsg:
+		m = m.handleOrphans(msg)
+
+	case depTreeMsg:
+		m = m.handleDepTree(msg)
+
+	case filesMsg:
+		m = m.handleFiles(msg)
+
+	case graphExportedMsg:
+		m = m.handleGraphExported(msg)
+
+	case cachedVersionsMsg:
+		m = m.handleCachedVersions(msg)
+
+	case updatesMsg:
+		m.checkingUpdates = false
+		m.updatesWarn = nil
+		if msg.err == nil || errors.As(msg.err, &m.updatesWarn) {
+			m.updatesLoaded = true
+			m.updateItems = make([]Item, len(msg.pkgs))
+			for i, pkg := range msg.pkgs {
+				m.updateItems[i] = Item{Pkg: pkg}
+				if manager.IsSessionIgnored(pkg.Name) {
+					m.heldBack[pkg.Name] = true
+				}
 			}
 		}
 		m.updateListItems()
 
 	case PackageDetailMsg:
-		if msg.Name == m.loadingDetailsFor {
+		key := manager.Package(msg).QualifiedName()
+		if key == m.loadingDetailsFor {
 			m.loadingDetailsFor = ""
 		}
 		for i := range m.allItems {
-			if m.allItems[i].Pkg.Name == msg.Name {
-				m.allItems[i].Pkg = manager.Package(msg)
+			if m.allItems[i].Pkg.QualifiedName() == key && m.allItems[i].Pkg.IsAUR == msg.IsAUR {
+				m.allItems[i].Pkg = mergeDetails(m.allItems[i].Pkg, manager.Package(msg))
+			}
+		}
+		for i := range m.inventory {
+			if m.inventory[i].Pkg.Name == msg.Name && msg.IsInstalled {
+				// The inventory knows where the package came from.
+				pkg := mergeDetails(m.inventory[i].Pkg, manager.Package(msg))
+				pkg.Repository = m.inventory[i].Pkg.Repository
+				pkg.Foreign = m.inventory[i].Pkg.Foreign
+				m.inventory[i].Pkg = pkg
+			}
+		}
+		for i := range m.orphanItems {
+			if m.orphanItems[i].Pkg.Name == msg.Name && msg.IsInstalled {
+				m.orphanItems[i].Pkg = manager.Package(msg)
+			}
+		}
+		for i := range m.updateItems {
+			if m.updateItems[i].Pkg.QualifiedName() == key && m.updateItems[i].Pkg.IsAUR == msg.IsAUR {
+				// Details of an installed package describe the installed
+				// version; keep the pending one.
+				pkg := manager.Package(msg)
+				pkg.Version = m.updateItems[i].Pkg.Version
+				pkg.InstalledVersion = m.updateItems[i].Pkg.InstalledVersion
+				m.updateItems[i].Pkg = pkg
 			}
 		}
 		m.updateListItems()
 
+	case detailsErrMsg:
+		m.handleDetailsErr(msg)
+
 	case bulkDoneMsg:
 		m.markedInstall = make(map[string]manager.Package)
 		m.markedRemove = make(map[string]manager.Package)
@@ -482,35 +812,223 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	if i, ok := m.list.SelectedItem().(Item); ok {
-		if i.Pkg.Name != m.lastSelectedPkg {
-			m.lastSelectedPkg = i.Pkg.Name
+		if i.Pkg.QualifiedName() != m.lastSelectedPkg {
+			m.lastSelectedPkg = i.Pkg.QualifiedName()
 			m.showingPKGBUILD = false
+			m.showingTree = false
+			m.showingFiles = false
+			m.filesSearching = false
 			m.loadingDetailsFor = ""
 			m.viewport.GotoTop()
 		}
 
-		if m.showingPKGBUILD {
+		if m.showingTree {
+			m.viewport.SetContent(m.renderDepTree(m.viewport.Width))
+		} else if m.showingFiles {
+			m.viewport.SetContent(m.renderFiles(m.viewport.Width))
+		} else if m.showingPKGBUILD {
 			m.viewport.SetContent(renderPKGBUILD(i.Pkg, m.viewport.Width))
+		} else if err := m.detailsErrs[i.Pkg.QualifiedName()]; err != nil && !i.Pkg.Detailed {
+			m.viewport.SetContent(renderDetailsError(i.Pkg, err, m.viewport.Width))
 		} else {
-			m.viewport.SetContent(renderDescription(i.Pkg, m.viewport.Width))
+			m.viewport.SetContent(renderDescription(i.Pkg, i.Query, m.viewport.Width))
 		}
 
-		if !i.Pkg.Detailed && m.loadingDetailsFor != i.Pkg.Name {
-			m.loadingDetailsFor = i.Pkg.Name
+		if !i.Pkg.Detailed && m.loadingDetailsFor != i.Pkg.QualifiedName() && m.detailsErrs[i.Pkg.QualifiedName()] == nil {
+			m.loadingDetailsFor = i.Pkg.QualifiedName()
 			cmds = append(cmds, fetchDetails(i.Pkg))
 		}
+	} else if h, ok := m.list.SelectedItem().(historyItem); ok {
+		m.viewport.SetContent(renderTransaction(h, m.viewport.Width))
 	} else {
 		m.viewport.SetContent("")
 	}
 	return m, tea.Batch(cmds...)
 }
 
+// setTab switches to the tab at idx, wrapping around, and returns the command
+// that loads the tab's contents if it needs any.
+func (m *Model) setTab(idx int) tea.Cmd {
+	m.activeTab = (idx + len(m.tabs)) % len(m.tabs)
+	m.updateListItems()
+	switch {
+	case m.tabs[m.activeTab] == "UPDATES" && !m.updatesLoaded:
+		return m.loadUpdates()
+	case m.tabs[m.activeTab] == "ORPHANS" && !m.orphansLoaded:
+		return m.loadOrphans()
+	case m.tabs[m.activeTab] == "HISTORY" && !m.historyLoaded:
+		return m.loadHistory()
+	case !m.isLocalTab() && m.searchedQuery != m.currentQuery:
+		// The query changed while a local tab was filtering.
+		return m.runSearch()
+	}
+	return nil
+}
+
+// isLocalTab reports whether the active tab filters local data with the
+// search input instead of searching the repositories and the AUR.
+func (m Model) isLocalTab() bool {
+	switch m.tabs[m.activeTab] {
+	case "INSTALLED", "ORPHANS", "HISTORY":
+		return true
+	}
+	return false
+}
+
+// runSearch searches for currentQuery, or only refilters on a local tab.
+func (m *Model) runSearch() tea.Cmd {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+
+	if m.isLocalTab() {
+		m.updateListItems()
+		return nil
+	}
+
+	m.searchedQuery = m.currentQuery
+	if m.currentQuery == "" {
+		m.searchSeq++
+		m.sourceLoading = nil
+		m.sourceErrs = nil
+		m.allItems = []Item{}
+		m.updateListItems()
+		m.isSearching = false
+		return nil
+	}
+	if q, err := manager.ParsePackageQuery(m.currentQuery); err != nil || (q.Text == "" && len(q.Filters) == 0) {
+		// Keep the last results while the query is half typed;
+		// updateListItems says what is wrong with it.
+		m.updateListItems()
+		m.isSearching = false
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.searchCancel = cancel
+	m.startSearch()
+	return performSearch(ctx, m.searchSeq, m.currentQuery, m.searchDesc)
+}
+
+// startSearch numbers a new search and marks every source as loading. The
+// last results stay listed until the first source answers.
+func (m *Model) startSearch() {
+	m.searchSeq++
+	m.isSearching = true
+	m.sourceLoading = make(map[manager.SearchSource]bool)
+	for _, source := range manager.SearchSources {
+		m.sourceLoading[source] = true
+	}
+	m.sourceErrs = make(map[manager.SearchSource]error)
+	m.dropToasts("search:")
+}
+
+// handleSearchResults merges what one source found with what the others
+// already returned. A failing source gets an error badge and a toast without
+// blanking the rest of the list.
+func (m *Model) handleSearchResults(msg searchResultsMsg) {
+	if m.resultsSeq != msg.seq {
+		m.resultsSeq = msg.seq
+		m.sourcePkgs = make(map[manager.SearchSource][]manager.Package)
+	}
+	delete(m.sourceLoading, msg.source)
+	m.isSearching = len(m.sourceLoading) > 0
+	if msg.err != nil {
+		m.sourceErrs[msg.source] = msg.err
+		if hint := refinement(msg.query, m.searchDesc, msg.err); hint != "" {
+			// Searching again won't help until the query changes, and
+			// there's nothing to suggest once it already has.
+			if msg.query == m.input.Value() {
+				m.pushError("search:"+string(msg.source), "Searching "+sourceLabels[msg.source], msg.err, hint, nil)
+			}
+		} else if !errors.Is(msg.err, context.Canceled) {
+			m.pushError("search:"+string(msg.source), "Searching "+sourceLabels[msg.source], msg.err, "", func(m *Model) tea.Cmd {
+				return m.runSearch()
+			})
+		}
+	} else {
+		m.sourcePkgs[msg.source] = msg.pkgs
+	}
+
+	var pkgs []manager.Package
+	for _, source := range manager.SearchSources {
+		pkgs = append(pkgs, m.sourcePkgs[source]...)
+	}
+	if q, err := manager.ParsePackageQuery(msg.query); err == nil {
+		manager.RankResults(pkgs, q.Text)
+	}
+	m.allItems = make([]Item, len(pkgs))
+	for i, pkg := range pkgs {
+		m.allItems[i] = Item{Pkg: pkg}
+	}
+	m.updateListItems()
+}
+
+// jumpToPackage searches for name on the ALL tab and focuses its details
+// once it shows up in the results.
+func (m *Model) jumpToPackage(name string) tea.Cmd {
+	m.jumpTo = name
+	m.input.SetValue(name)
+	m.currentQuery = name
+	cmd := m.setTab(0)
+	m.selectJumpTarget()
+	return cmd
+}
+
+func (m *Model) selectJumpTarget() {
+	if m.jumpTo == "" {
+		return
+	}
+	for idx, it := range m.list.Items() {
+		if i, ok := it.(Item); ok && i.Pkg.Name == m.jumpTo {
+			m.list.Select(idx)
+			m.focusSide = 1
+			m.searching = false
+			m.input.Blur()
+			m.jumpTo = ""
+			return
+		}
+	}
+}
+
 func (m *Model) updateListItems() {
 	var filtered []list.Item
-	mode := tabs[m.activeTab]
+	mode := m.tabs[m.activeTab]
+
+	m.queryErr = ""
+	if mode == "HISTORY" {
+		m.list.SetItems(m.historyItems())
+		return
+	}
+
+	q, err := manager.ParsePackageQuery(m.currentQuery)
+	if err != nil {
+		m.queryErr = err.Error()
+	}
+
+	if mode == "INSTALLED" {
+		m.list.SetItems(m.inventoryListItems(q))
+		return
+	}
+
+	if mode == "ORPHANS" {
+		m.list.SetItems(m.orphanListItems(q))
+		return
+	}
+
+	if mode == "UPDATES" {
+		for i := range m.updateItems {
+			m.updateItems[i].Held = m.heldBack[m.updateItems[i].Pkg.Name]
+			filtered = append(filtered, m.updateItems[i])
+		}
+		m.list.SetItems(filtered)
+		return
+	}
+
 	for i := range m.allItems {
-		m.allItems[i].Query = m.currentQuery
-		_, m.allItems[i].MarkedInst = m.markedInstall[m.allItems[i].Pkg.Name]
+		m.allItems[i].Query = searchTerm(q.Text)
+		_, m.allItems[i].MarkedInst = m.markedInstall[m.allItems[i].Pkg.QualifiedName()]
 		_, m.allItems[i].MarkedRem = m.markedRemove[m.allItems[i].Pkg.Name]
 
 		item := m.allItems[i]
@@ -525,8 +1043,8 @@ func (m *Model) updateListItems() {
 			if !item.Pkg.IsAUR {
 				filtered = append(filtered, item)
 			}
-		case "INSTALLED":
-			if item.Pkg.IsInstalled {
+		default:
+			if !item.Pkg.IsAUR && item.Pkg.Repository == mode {
 				filtered = append(filtered, item)
 			}
 		}
@@ -534,14 +1052,35 @@ func (m *Model) updateListItems() {
 	m.list.SetItems(filtered)
 }
 
-func performSearch(ctx context.Context, query string) tea.Cmd {
+// performSearch searches every source separately, so each answers with its
+// own searchResultsMsg as soon as it is done.
+func performSearch(ctx context.Context, seq int, query string, descriptions bool) tea.Cmd {
 	if query == "" {
 		return nil
 	}
-	return func() tea.Msg {
-		res, err := manager.SearchContext(ctx, query)
-		return searchResultsMsg{query: query, pkgs: res, err: err}
+	q, parseErr := manager.ParsePackageQuery(query)
+	q.Descriptions = descriptions
+	var cmds []tea.Cmd
+	for _, source := range manager.SearchSources {
+		cmds = append(cmds, func() tea.Msg {
+			if parseErr != nil {
+				return searchResultsMsg{seq: seq, query: query, source: source, err: parseErr}
+			}
+			pkgs, err := q.SearchIn(ctx, source)
+			return searchResultsMsg{seq: seq, query: query, source: source, pkgs: pkgs, err: err}
+		})
 	}
+	return tea.Batch(cmds...)
+}
+
+// searchTerm is the part of a query's text that names are matched against,
+// for highlighting.
+func searchTerm(text string) string {
+	if strings.HasPrefix(text, "file:") {
+		return ""
+	}
+	_, term := manager.ParseSearchQuery(text)
+	return term
 }
 
 func refreshInstalledStatus() tea.Msg {
@@ -549,7 +1088,92 @@ func refreshInstalledStatus() tea.Msg {
 	return InstalledMapMsg(manager.GetInstalledCache())
 }
 
-func renderDescription(p manager.Package, width int) string {
+// mergeDetails applies fetched details to a listed package. Details of an
+// installed package come from the local database and carry the installed
+// version, so the available one the list already had is kept.
+func mergeDetails(listed, details manager.Package) manager.Package {
+	if listed.InstalledVersion != "" {
+		if details.IsInstalled && !details.IsAUR {
+			details.Version = listed.Version
+		}
+		details.InstalledVersion = listed.InstalledVersion
+	}
+	return details
+}
+
+// updateAvailable reports whether the listed version is newer than the
+// installed one.
+func updateAvailable(p manager.Package) bool {
+	return p.IsInstalled && p.InstalledVersion != "" && manager.Vercmp(p.Version, p.InstalledVersion) > 0
+}
+
+// versionLabel is "installed → available" with the newer version
+// highlighted, or just the version when they match.
+func versionLabel(p manager.Package) string {
+	if p.InstalledVersion == "" || p.InstalledVersion == p.Version {
+		return p.Version
+	}
+	return fmt.Sprintf("%s → %s", p.InstalledVersion, versionHighlight(p))
+}
+
+// versionHighlight is the available version, highlighted when it is newer
+// than the installed one.
+func versionHighlight(p manager.Package) string {
+	if updateAvailable(p) {
+		return lipgloss.NewStyle().Foreground(CurrentTheme.Green).Bold(true).Render(p.Version)
+	}
+	return p.Version
+}
+
+// highlightWords renders text in style with every occurrence of the words
+// of query highlighted, ignoring case.
+func highlightWords(text, query string, style lipgloss.Style) string {
+	lowText := strings.ToLower(text)
+	if len(lowText) != len(text) {
+		// Lowercasing moved the byte offsets; not worth highlighting.
+		return style.Render(text)
+	}
+	matched := make([]bool, len(text))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		for start := 0; ; {
+			idx := strings.Index(lowText[start:], word)
+			if idx < 0 {
+				break
+			}
+			for i := start + idx; i < start+idx+len(word); i++ {
+				matched[i] = true
+			}
+			start += idx + len(word)
+		}
+	}
+	if !slices.Contains(matched, true) {
+		return style.Render(text)
+	}
+
+	highlight := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true)
+	var sb strings.Builder
+	for start := 0; start < len(text); {
+		end := start
+		for end < len(text) && matched[end] == matched[start] {
+			end++
+		}
+		if matched[start] {
+			sb.WriteString(highlight.Render(text[start:end]))
+		} else {
+			sb.WriteString(style.Render(text[start:end]))
+		}
+		start = end
+	}
+	return sb.String()
+}
+
+func updateBadge() string {
+	return lipgloss.NewStyle().Foreground(CurrentTheme.Base).Background(CurrentTheme.Green).Bold(true).Render(" update available ")
+}
+
+// renderDescription shows p's details, with the words of query highlighted
+// in its description.
+func renderDescription(p manager.Package, query string, width int) string {
 	if !p.Detailed {
 		header := lipgloss.NewStyle().Foreground(CurrentTheme.RepoOfficial).Bold(true).Render(p.Name)
 		if p.IsAUR {
@@ -567,6 +1191,9 @@ func renderDescription(p manager.Package, width int) string {
 	if p.IsAUR {
 		headerStyle = headerStyle.Foreground(CurrentTheme.RepoAUR)
 		fmt.Fprintf(&sb, "\n%s\n\n", headerStyle.Render(p.Name))
+		if updateAvailable(p) {
+			fmt.Fprintf(&sb, "%s\n\n", updateBadge())
+		}
 
 		row := func(k, v string) {
 			if v == "" {
@@ -576,8 +1203,13 @@ func renderDescription(p manager.Package, width int) string {
 		}
 
 		row("Repository", "AUR")
-		row("Version", p.Version)
-		row("Description", p.Description)
+		row("Version", versionHighlight(p))
+		if p.InstalledVersion != "" && p.InstalledVersion != p.Version {
+			row("Installed", p.InstalledVersion)
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render("Description"), highlightWords(p.Description, query, valStyle))
+		}
 		row("URL", p.URL)
 		row("Maintainer", p.Maintainer)
 		row("Votes", fmt.Sprintf("%d (Pop: %.2f)", p.Votes, p.Popularity))
@@ -598,10 +1230,13 @@ func renderDescription(p manager.Package, width int) string {
 			fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render("Make Deps"), valStyle.Render(strings.Join(p.MakeDepends, "  ")))
 		}
 
-		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("\n[ PKGBUILD ]"))
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("\n[ p: PKGBUILD ]  [ t: Dependency Tree ]"))
 
 	} else {
 		fmt.Fprintf(&sb, "\n%s\n\n", headerStyle.Render(p.Name))
+		if updateAvailable(p) {
+			fmt.Fprintf(&sb, "%s\n\n", updateBadge())
+		}
 
 		row := func(k, v string) {
 			fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render(k), valStyle.Render(v))
@@ -614,9 +1249,13 @@ func renderDescription(p manager.Package, width int) string {
 			}
 		}
 
+		row("Repository", repoLabel(p))
 		row("Name", p.Name)
-		row("Version", p.Version)
-		row("Description", p.Description)
+		row("Version", versionHighlight(p))
+		if p.InstalledVersion != "" && p.InstalledVersion != p.Version {
+			row("Installed", p.InstalledVersion)
+		}
+		fmt.Fprintf(&sb, "%s : %s\n", keyStyle.Render("Description"), highlightWords(p.Description, query, valStyle))
 		row("Architecture", p.Architecture)
 		row("URL", p.URL)
 		listRow("Licenses", p.Licenses)
@@ -642,6 +1281,12 @@ func renderDescription(p manager.Package, width int) string {
 		row("Install Date", dateStr(p.InstallDate))
 		row("Install Reason", p.InstallReason)
 		row("Validated By", p.ValidatedBy)
+
+		hint := "\n[ t: Dependency Tree ]  [ F: Files ]"
+		if p.IsInstalled {
+			hint += "  [ r: Why Installed ]  [ d: Downgrade ]"
+		}
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render(hint))
 	}
 
 	return lipgloss.NewStyle().Width(width).Render(sb.String())
@@ -674,15 +1319,6 @@ func renderPKGBUILD(p manager.Package, width int) string {
 	return lipgloss.NewStyle().Width(width).Render(sb.String())
 }
 
-func fetchDetails(p manager.Package) tea.Cmd {
-	return func() tea.Msg {
-		if err := manager.GetPackageDetails(&p); err != nil {
-			return nil
-		}
-		return PackageDetailMsg(p)
-	}
-}
-
 func fetchPKGBUILD(p manager.Package) tea.Cmd {
 	return func() tea.Msg {
 		build, err := manager.GetPKGBUILD(p.Name)
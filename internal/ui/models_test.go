@@ -0,0 +1,981 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func newTestModel(t *testing.T, pkgs ...manager.Package) (Model, *manager.FakeBackend) {
+	t.Helper()
+	fake := manager.NewFakeBackend(pkgs...)
+	manager.SetBackend(fake)
+	t.Cleanup(func() { manager.SetBackend(nil) })
+
+	ApplyTheme("")
+	var model tea.Model = NewModel()
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	return model.(Model), fake
+}
+
+// runCmd feeds what cmd returns back into model, running every command of
+// a batch, like the per-source searches.
+func runCmd(model tea.Model, cmd tea.Cmd) tea.Model {
+	if cmd == nil {
+		return model
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, c := range batch {
+			model = runCmd(model, c)
+		}
+		return model
+	}
+	model, _ = model.Update(msg)
+	return model
+}
+
+func typeQuery(t *testing.T, m Model, query string) Model {
+	t.Helper()
+	var model tea.Model = m
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(query)})
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected a search command after Enter")
+	}
+	model = runCmd(model, cmd)
+	return model.(Model)
+}
+
+func TestSearchPopulatesList(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "neovim", Version: "0.10.0-1"},
+		manager.Package{Name: "neovim-git", IsAUR: true, Votes: 5},
+		manager.Package{Name: "emacs"},
+	)
+
+	m = typeQuery(t, m, "neovim")
+
+	items := m.list.Items()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d", len(items))
+	}
+	if first := items[0].(Item).Pkg.Name; first != "neovim" {
+		t.Errorf("Expected exact match first, got %q", first)
+	}
+}
+
+func TestBulkApplyUsesBackend(t *testing.T) {
+	m, fake := newTestModel(t,
+		manager.Package{Name: "neovim"},
+		manager.Package{Name: "neovim-git", IsAUR: true},
+	)
+	m = typeQuery(t, m, "neovim")
+
+	var model tea.Model = m
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'I'}})
+
+	txs := fake.Transactions()
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txs))
+	}
+	if len(txs[0].Install) != 1 || txs[0].Install[0] != "neovim" {
+		t.Errorf("Expected neovim in official installs, got %v", txs[0].Install)
+	}
+	if len(txs[0].InstallAUR) != 1 || txs[0].InstallAUR[0] != "neovim-git" {
+		t.Errorf("Expected neovim-git in AUR installs, got %v", txs[0].InstallAUR)
+	}
+}
+
+func TestInstallUsesRepositoryQualifiedName(t *testing.T) {
+	m, fake := newTestModel(t,
+		manager.Package{Name: "linux", Repository: "internal"},
+	)
+	m = typeQuery(t, m, "linux")
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected an install command")
+	}
+
+	txs := fake.Transactions()
+	if len(txs) != 1 || len(txs[0].Install) != 1 || txs[0].Install[0] != "internal/linux" {
+		t.Errorf("Expected install of internal/linux, got %+v", txs)
+	}
+	if desc := model.(Model).list.Items()[0].(Item).Description(); !strings.Contains(desc, "internal") {
+		t.Errorf("Expected list description to show the repository, got %q", desc)
+	}
+}
+
+func TestRepositoryTabs(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "mesa", Repository: "extra"},
+		manager.Package{Name: "mesa-git", Repository: "chaotic-aur"},
+		manager.Package{Name: "mesa-aco", IsAUR: true},
+	)
+	m = typeQuery(t, m, "mesa")
+
+	idx := slices.Index(m.tabs, "chaotic-aur")
+	if idx < 0 {
+		t.Fatalf("Expected a chaotic-aur tab, got %v", m.tabs)
+	}
+	m.activeTab = idx
+	m.updateListItems()
+
+	items := m.list.Items()
+	if len(items) != 1 || items[0].(Item).Pkg.Name != "mesa-git" {
+		t.Errorf("Expected only mesa-git in the chaotic-aur tab, got %v", items)
+	}
+}
+
+func TestUpdatesTab(t *testing.T) {
+	m, fake := newTestModel(t)
+	fake.PendingUpdates = []manager.Package{
+		{Name: "linux", Repository: "core", InstalledVersion: "6.9.1-1", Version: "6.10.1-1", IsInstalled: true},
+		{Name: "yay", Repository: "aur", IsAUR: true, InstalledVersion: "12.3.4-1", Version: "12.3.5-1", IsInstalled: true},
+	}
+
+	cmd := m.setTab(slices.Index(m.tabs, "UPDATES"))
+	if cmd == nil {
+		t.Fatal("Expected switching to UPDATES to start an update check")
+	}
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+
+	items := m.list.Items()
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 updates, got %d", len(items))
+	}
+	if desc := items[0].(Item).Description(); !strings.Contains(desc, "6.9.1-1 → 6.10.1-1") {
+		t.Errorf("Expected both versions in the description, got %q", desc)
+	}
+	if m.setTab(m.activeTab) != nil {
+		t.Error("Expected loaded updates to be reused")
+	}
+}
+
+func TestUpdatesTabPartialCheck(t *testing.T) {
+	m, fake := newTestModel(t)
+	fake.PendingUpdates = []manager.Package{
+		{Name: "linux", Repository: "core", InstalledVersion: "6.9.1-1", Version: "6.10.1-1", IsInstalled: true},
+	}
+	fake.UpdatesErr = &manager.UpdatesWarning{Stale: errors.New("pacman: not found"), AUR: errors.New("503 Service Unavailable")}
+
+	cmd := m.setTab(slices.Index(m.tabs, "UPDATES"))
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+	if len(m.list.Items()) != 1 || !m.updatesLoaded {
+		t.Fatalf("Expected the repository update despite the warning, got %d items", len(m.list.Items()))
+	}
+	if badges := m.updatesBadges(); !strings.Contains(badges, "out of date") || !strings.Contains(badges, "AUR not checked") {
+		t.Errorf("Expected stale and AUR badges, got %q", badges)
+	}
+}
+
+func TestSelectiveUpdate(t *testing.T) {
+	m, fake := newTestModel(t)
+	fake.PendingUpdates = []manager.Package{
+		{Name: "linux", Repository: "core", InstalledVersion: "6.9.1-1", Version: "6.10.1-1", IsInstalled: true},
+		{Name: "mesa", Repository: "extra", InstalledVersion: "24.1-1", Version: "24.2-1", IsInstalled: true},
+		{Name: "yay", Repository: "aur", IsAUR: true, InstalledVersion: "12.3.4-1", Version: "12.3.5-1", IsInstalled: true},
+	}
+	cmd := m.setTab(slices.Index(m.tabs, "UPDATES"))
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+
+	// Hold back linux, the first entry.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+	if !model.(Model).list.Items()[0].(Item).Held {
+		t.Fatal("Expected linux to be held back")
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'U'}})
+
+	txs := fake.Transactions()
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txs))
+	}
+	tx := txs[0]
+	if !tx.Upgrade || !tx.RepoOnly || !slices.Equal(tx.Ignore, []string{"linux"}) || !slices.Equal(tx.InstallAUR, []string{"yay"}) {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+}
+
+func TestSelectiveUpdateAllHeld(t *testing.T) {
+	m, fake := newTestModel(t)
+	fake.PendingUpdates = []manager.Package{
+		{Name: "linux", Repository: "core", InstalledVersion: "6.9.1-1", Version: "6.10.1-1", IsInstalled: true},
+	}
+	cmd := m.setTab(slices.Index(m.tabs, "UPDATES"))
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{' '}})
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'U'}})
+
+	if m = model.(Model); cmd != nil || m.notice != "Nothing to upgrade (1 held)" {
+		t.Errorf("Expected a notice instead of an upgrade, got %q", m.notice)
+	}
+}
+
+func TestUpgradeWaitsForNewsAcknowledgement(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.searching = false
+	m.focusSide = 0
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'U'}})
+	if cmd == nil || !model.(Model).checkingNews {
+		t.Fatal("Expected U to check the news first")
+	}
+
+	model, _ = model.Update(newsMsg{items: []manager.NewsItem{
+		{Title: "Foo requires manual intervention", ManualIntervention: true},
+	}})
+	if !model.(Model).showingNews {
+		t.Fatal("Expected the news to be shown")
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !model.(Model).showingNews {
+		t.Fatal("Expected Enter not to acknowledge manual intervention")
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if cmd == nil || model.(Model).showingNews {
+		t.Fatal("Expected 'y' to acknowledge and start the upgrade")
+	}
+}
+
+func TestUpgradeReportsUnreachableNews(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.searching = false
+	m.focusSide = 0
+
+	var model tea.Model = m
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'U'}})
+	model, cmd := model.Update(newsMsg{err: errors.New("connection refused")})
+	m = model.(Model)
+	if cmd != nil || !m.showingNews || len(m.toasts) != 1 {
+		t.Fatal("Expected the feed error to be shown before upgrading")
+	}
+	if !strings.Contains(m.newsView(), "connection refused") {
+		t.Error("Expected the modal to say why the news couldn't be checked")
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || model.(Model).showingNews {
+		t.Fatal("Expected Enter to upgrade anyway")
+	}
+}
+
+func TestHistoryReadError(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.setTab(slices.Index(m.tabs, "HISTORY"))
+	m = m.handleHistory(historyMsg{err: errors.New("open /var/log/pacman.log: permission denied")})
+	if m.loadingHistory || len(m.toasts) != 1 || !strings.Contains(m.toasts[0].message, "permission denied") {
+		t.Errorf("Expected a toast with the cause, got %+v", m.toasts)
+	}
+}
+
+func TestHistoryTabJumpsToPackage(t *testing.T) {
+	m, fake := newTestModel(t, manager.Package{Name: "bash", Repository: "core", IsInstalled: true})
+	fake.Log = `[2024-07-01T12:00:05+0000] [ALPM] transaction started
+[2024-07-01T12:00:06+0000] [ALPM] upgraded bash (5.2.026-1 -> 5.2.026-2)
+[2024-07-01T12:00:06+0000] [ALPM] installed libnew (1.0-1)
+[2024-07-01T12:00:08+0000] [ALPM] transaction completed
+`
+	m.searching = false
+	m.input.Blur()
+	m.focusSide = 0
+
+	cmd := m.setTab(slices.Index(m.tabs, "HISTORY"))
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+	if n := len(model.(Model).list.Items()); n != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", n)
+	}
+
+	// Filtering happens in place without a search command.
+	m = model.(Model)
+	m.currentQuery = "bash"
+	if m.runSearch() != nil {
+		t.Error("Expected the HISTORY tab to filter locally")
+	}
+	if n := len(m.list.Items()); n != 1 {
+		t.Fatalf("Expected 1 bash entry, got %d", n)
+	}
+
+	model, cmd = tea.Model(m).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected Enter to search for the package")
+	}
+	model = runCmd(model, cmd)
+	m = model.(Model)
+	if m.tabs[m.activeTab] != "ALL" || m.focusSide != 1 {
+		t.Errorf("Expected the ALL tab with details focused, got tab %q focus %d", m.tabs[m.activeTab], m.focusSide)
+	}
+	if i, ok := m.list.SelectedItem().(Item); !ok || i.Pkg.Name != "bash" {
+		t.Errorf("Expected bash to be selected, got %v", m.list.SelectedItem())
+	}
+}
+
+func TestDowngradeFromCache(t *testing.T) {
+	m, fake := newTestModel(t, manager.Package{Name: "bash", Version: "5.2.026-2", Repository: "core", IsInstalled: true})
+	fake.Cache = map[string][]manager.CachedPackage{
+		"bash": {
+			{Name: "bash", Version: "5.2.026-2", Path: "/cache/bash-5.2.026-2-x86_64.pkg.tar.zst"},
+			{Name: "bash", Version: "5.2.026-1", Path: "/cache/bash-5.2.026-1-x86_64.pkg.tar.zst"},
+		},
+	}
+	t.Cleanup(func() { manager.IgnoreForSession("bash", false) })
+	m = typeQuery(t, m, "bash")
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if cmd == nil || !model.(Model).showingDowngrade {
+		t.Fatal("Expected 'd' to open the downgrade view")
+	}
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+	if m.downgradeIdx != 1 {
+		t.Errorf("Expected the older version to be preselected, got index %d", m.downgradeIdx)
+	}
+	if !strings.Contains(m.View(), "5.2.026-1") {
+		t.Error("Expected the cached versions to be listed")
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if !manager.IsSessionIgnored("bash") {
+		t.Error("Expected 'i' to ignore bash for the session")
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil || model.(Model).showingDowngrade {
+		t.Fatal("Expected Enter to close the view and run pacman -U")
+	}
+	txs := fake.Transactions()
+	if len(txs) != 1 || !slices.Equal(txs[0].InstallFiles, []string{"/cache/bash-5.2.026-1-x86_64.pkg.tar.zst"}) {
+		t.Errorf("Unexpected transactions: %+v", txs)
+	}
+}
+
+func TestDowngradeOutdatedPackage(t *testing.T) {
+	m, _ := newTestModel(t)
+	m.downgradePkg = manager.Package{Name: "bash", Version: "5.2.026-3", InstalledVersion: "5.2.026-2", IsInstalled: true}
+	m.showingDowngrade = true
+	m = m.handleCachedVersions(cachedVersionsMsg{name: "bash", versions: []manager.CachedPackage{
+		{Name: "bash", Version: "5.2.026-3"},
+		{Name: "bash", Version: "5.2.026-2"},
+		{Name: "bash", Version: "5.2.026-1"},
+	}})
+	if m.downgradeIdx != 2 {
+		t.Errorf("Expected the version below the installed one to be preselected, got index %d", m.downgradeIdx)
+	}
+	marked := 0
+	for _, line := range strings.Split(m.downgradeView(), "\n") {
+		if strings.Contains(line, "(installed)") {
+			marked++
+			if !strings.Contains(line, "5.2.026-2") {
+				t.Errorf("Expected 5.2.026-2 to be marked installed, got %q", line)
+			}
+		}
+	}
+	if marked != 1 {
+		t.Errorf("Expected one installed row, got %d", marked)
+	}
+}
+
+func TestOrphansTab(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "firefox", Repository: "extra", IsInstalled: true, Depends: []string{"gtk3"}, OptDepends: []string{"hunspell: spell checking"}},
+		manager.Package{Name: "gtk3", Repository: "extra", IsInstalled: true, Dependency: true},
+		manager.Package{Name: "hunspell", Repository: "extra", IsInstalled: true, Dependency: true, InstalledBytes: 1024, InstalledSize: "1.00 KiB"},
+		manager.Package{Name: "old-lib", Repository: "extra", IsInstalled: true, Dependency: true, InstalledBytes: 3072, InstalledSize: "3.00 KiB"},
+	)
+	m.searching = false
+	m.input.Blur()
+	m.focusSide = 0
+
+	cmd := m.setTab(slices.Index(m.tabs, "ORPHANS"))
+	var model tea.Model = m
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+	if n := len(m.list.Items()); n != 1 {
+		t.Fatalf("Expected 1 orphan, got %d", n)
+	}
+	if summary := m.orphanSummary(); summary != "1 orphan, 3.00 KiB reclaimable" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+	if n := len(m.list.Items()); n != 2 {
+		t.Fatalf("Expected optional deps to be listed too, got %d items", n)
+	}
+	if summary := m.orphanSummary(); summary != "2 orphans + optional, 4.00 KiB reclaimable" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("A")})
+	m = model.(Model)
+	if len(m.markedRemove) != 2 {
+		t.Errorf("Expected both orphans to be queued for removal, got %v", m.markedRemove)
+	}
+	for _, it := range m.list.Items() {
+		if !it.(Item).MarkedRem {
+			t.Errorf("Expected %s to show as queued", it.(Item).Pkg.Name)
+		}
+	}
+}
+
+func TestInstalledInventory(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "bash", Repository: "core", IsInstalled: true, InstalledBytes: 9000, InstallDate: 100},
+		manager.Package{Name: "glibc", Repository: "core", IsInstalled: true, Dependency: true, InstalledBytes: 50000, InstallDate: 50},
+		manager.Package{Name: "yay", IsAUR: true, IsInstalled: true, InstalledBytes: 100, InstallDate: 300},
+		manager.Package{Name: "vim", Repository: "extra"},
+	)
+	m.searching = false
+	m.input.Blur()
+	m.focusSide = 0
+
+	// No search needed: the whole inventory is read in the background at
+	// startup.
+	if !m.loadingInventory || m.setTab(slices.Index(m.tabs, "INSTALLED")) != nil {
+		t.Error("Expected the inventory loading from the start, not on the tab")
+	}
+	if view := m.View(); !strings.Contains(view, "Reading the local database...") {
+		t.Errorf("Expected a loading message on the INSTALLED tab:\n%s", view)
+	}
+	failed := m.handleInventory(inventoryMsg{err: errors.New("permission denied")})
+	if len(failed.toasts) != 1 || failed.toasts[0].retry == nil || failed.loadingInventory {
+		t.Errorf("Expected a failed read to end loading with a retry toast, got %+v", failed.toasts)
+	}
+	m = m.handleInventory(m.loadInventory()().(inventoryMsg))
+	names := func() []string {
+		var out []string
+		for _, it := range m.list.Items() {
+			out = append(out, it.(Item).Pkg.Name)
+		}
+		return out
+	}
+	if got := names(); !slices.Equal(got, []string{"bash", "glibc", "yay"}) {
+		t.Fatalf("Expected all installed packages, got %v", got)
+	}
+
+	press := func(key string) {
+		t.Helper()
+		model, _ := tea.Model(m).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		m = model.(Model)
+	}
+
+	press("s")
+	if got := names(); !slices.Equal(got, []string{"glibc", "bash", "yay"}) {
+		t.Errorf("Expected largest first, got %v", got)
+	}
+	press("s")
+	if got := names(); !slices.Equal(got, []string{"yay", "bash", "glibc"}) {
+		t.Errorf("Expected most recently installed first, got %v", got)
+	}
+
+	press("f")
+	if got := names(); !slices.Equal(got, []string{"yay", "bash"}) {
+		t.Errorf("Expected only explicit packages, got %v", got)
+	}
+	press("f")
+	press("f")
+	press("f")
+	if got := names(); !slices.Equal(got, []string{"yay"}) {
+		t.Errorf("Expected only foreign packages, got %v", got)
+	}
+	if !strings.Contains(m.list.Items()[0].(Item).Description(), "foreign") {
+		t.Errorf("Expected foreign packages to be labelled, got %q", m.list.Items()[0].(Item).Description())
+	}
+
+	m.inventoryFilter = 0
+	m.currentQuery = "gl"
+	m.runSearch()
+	if got := names(); !slices.Equal(got, []string{"glibc"}) {
+		t.Errorf("Expected the query to filter in place, got %v", got)
+	}
+}
+
+func TestInstalledVersusAvailableVersion(t *testing.T) {
+	m, fake := newTestModel(t,
+		manager.Package{Name: "bash", Version: "5.2.026-2", Repository: "core", IsInstalled: true},
+		manager.Package{Name: "bash-completion", Version: "2.14.0-1", Repository: "extra"},
+	)
+	fake.InstalledPkgs["bash"] = true
+	m = typeQuery(t, m, "bash")
+
+	// Pretend an older bash is installed.
+	var model tea.Model = m
+	model, _ = model.Update(InstalledMapMsg{"bash": "5.2.026-1"})
+	m = model.(Model)
+
+	var bash Item
+	for _, it := range m.list.Items() {
+		if it.(Item).Pkg.Name == "bash" {
+			bash = it.(Item)
+		}
+	}
+	if bash.Pkg.InstalledVersion != "5.2.026-1" || !updateAvailable(bash.Pkg) {
+		t.Fatalf("Expected an update from 5.2.026-1, got %+v", bash.Pkg)
+	}
+	if desc := bash.Description(); !strings.Contains(desc, "5.2.026-1 → ") || !strings.Contains(desc, "⬆") {
+		t.Errorf("Expected both versions and a badge in %q", desc)
+	}
+
+	// Details of an installed package describe the local copy; the list
+	// keeps the available version.
+	details := bash.Pkg
+	details.Version = "5.2.026-1"
+	details.InstalledVersion = ""
+	details.Detailed = true
+	model, _ = model.Update(PackageDetailMsg(details))
+	m = model.(Model)
+	for _, it := range m.allItems {
+		if it.Pkg.Name == "bash" && (it.Pkg.Version != "5.2.026-2" || it.Pkg.InstalledVersion != "5.2.026-1") {
+			t.Errorf("Expected versions to survive details, got %s / %s", it.Pkg.Version, it.Pkg.InstalledVersion)
+		}
+	}
+	if view := renderDescription(mergeDetails(bash.Pkg, details), "", 80); !strings.Contains(view, "update available") || !strings.Contains(view, "Installed") {
+		t.Error("Expected the detail panel to show the installed version and the badge")
+	}
+}
+
+func TestDependencyTreeView(t *testing.T) {
+	m, fake := newTestModel(t,
+		manager.Package{Name: "app", Repository: "extra", Depends: []string{"libfoo", "ghost"}},
+		manager.Package{Name: "libfoo", Repository: "extra", Depends: []string{"glibc"}},
+		manager.Package{Name: "glibc", Repository: "core", IsInstalled: true},
+	)
+	m = typeQuery(t, m, "app")
+	// Offline, ghost can't be looked up in the AUR; the tree still opens.
+	fake.SourceErrs = map[manager.SearchSource]error{manager.SourceAUR: manager.ErrOffline}
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	if cmd == nil || !model.(Model).showingTree || model.(Model).focusSide != 1 {
+		t.Fatal("Expected 't' to open the dependency tree with the details focused")
+	}
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+
+	if rows := m.treeRows(); len(rows) != 3 {
+		t.Fatalf("Expected the root and its 2 dependencies, got %d rows", len(rows))
+	}
+	view := m.renderDepTree(80)
+	if !strings.Contains(view, "libfoo") || !strings.Contains(view, "missing") {
+		t.Errorf("Expected libfoo and a missing ghost in the tree:\n%s", view)
+	}
+	if !strings.Contains(view, "AUR unavailable") {
+		t.Errorf("Expected a note that the AUR couldn't be asked:\n%s", view)
+	}
+
+	// Expand libfoo to reveal glibc.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	m = model.(Model)
+	rows := m.treeRows()
+	if len(rows) != 4 || rows[2].node.Name() != "glibc" {
+		t.Fatalf("Expected glibc under libfoo after expanding, got %d rows", len(rows))
+	}
+
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Expected Enter to search for the dependency")
+	}
+	model = runCmd(model, cmd)
+	m = model.(Model)
+	if i, ok := m.list.SelectedItem().(Item); !ok || i.Pkg.Name != "libfoo" || m.showingTree {
+		t.Errorf("Expected libfoo to be selected with the tree closed, got %v", m.list.SelectedItem())
+	}
+}
+
+func TestWhyInstalledView(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "firefox", Repository: "extra", IsInstalled: true, Depends: []string{"gtk3"}},
+		manager.Package{Name: "gtk3", Repository: "extra", IsInstalled: true, Dependency: true, Depends: []string{"glib2"}},
+		manager.Package{Name: "glib2", Repository: "core", IsInstalled: true, Dependency: true},
+	)
+	m = typeQuery(t, m, "glib2")
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if cmd == nil || !model.(Model).treeReverse {
+		t.Fatal("Expected 'r' to open the reverse dependency tree")
+	}
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+
+	view := m.renderDepTree(100)
+	if !strings.Contains(view, "Installed because of") || !strings.Contains(view, "gtk3 → glib2") {
+		t.Errorf("Expected the firefox → gtk3 → glib2 chain:\n%s", view)
+	}
+
+	// The cursor row is the first line after the header.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = model.(Model)
+	if rows := m.treeRows(); m.treeCursor != 1 || rows[1].node.Name() != "gtk3" {
+		t.Errorf("Expected the cursor on gtk3, got %d", m.treeCursor)
+	}
+}
+
+func TestExportGraph(t *testing.T) {
+	t.Chdir(t.TempDir())
+	m, _ := newTestModel(t,
+		manager.Package{Name: "app", Repository: "extra", Depends: []string{"libfoo"}},
+		manager.Package{Name: "libfoo", Repository: "extra", Depends: []string{"libbar"}},
+		manager.Package{Name: "libbar", Repository: "extra"},
+	)
+	m = typeQuery(t, m, "app")
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("X")})
+	if cmd == nil {
+		t.Fatal("Expected 'X' to export the graph")
+	}
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+
+	path, _ := filepath.Abs("gopac-app.mmd")
+	if m.notice != "Graph written to "+path {
+		t.Fatalf("Unexpected notice %q (error %q)", m.notice, m.queryErr)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "n0 --> n1") || !strings.Contains(string(data), "n1 --> n2") {
+		t.Errorf("Expected app --> libfoo --> libbar in the exported file, got %q (%v)", data, err)
+	}
+
+	// With the tree open, only what it shows is exported.
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	model, _ = model.Update(cmd())
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("X")})
+	model, _ = model.Update(cmd())
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "n0 --> n1") || strings.Contains(string(data), "libbar") {
+		t.Errorf("Expected only app --> libfoo with libfoo collapsed, got %q", data)
+	}
+
+	// Failures go to the toasts and the error log.
+	m = model.(Model).handleGraphExported(graphExportedMsg{err: errors.New("permission denied")})
+	if m.notice != "" || len(m.toasts) != 1 || len(m.errorLog) != 1 {
+		t.Errorf("Expected a toast instead of the notice, got %q and %+v", m.notice, m.toasts)
+	}
+}
+
+func TestFilesView(t *testing.T) {
+	m, fake := newTestModel(t, manager.Package{Name: "bash", Repository: "core", IsInstalled: true})
+	fake.FileLists = map[string][]string{
+		"bash": {"/etc/", "/etc/bash.bashrc", "/usr/", "/usr/bin/", "/usr/bin/bash", "/usr/bin/sh", "/usr/share/", "/usr/share/doc/", "/usr/share/doc/bash/", "/usr/share/doc/bash/FAQ"},
+	}
+	m = typeQuery(t, m, "bash")
+
+	var model tea.Model = m
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("F")})
+	if cmd == nil || !model.(Model).showingFiles || model.(Model).focusSide != 1 {
+		t.Fatal("Expected 'F' to open the file list with the details focused")
+	}
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+
+	var names []string
+	for _, row := range m.fileRows() {
+		names = append(names, row.node.name)
+	}
+	if !slices.Equal(names, []string{"/", "etc/", "usr/"}) {
+		t.Fatalf("Expected / with etc/ and usr/ collapsed, got %v", names)
+	}
+	view := m.renderFiles(80)
+	if !strings.Contains(view, "4 files in 6 directories") || !strings.Contains(view, "usr/ (3)") {
+		t.Errorf("Expected the file and per-directory counts:\n%s", view)
+	}
+
+	// Expand usr/.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyDown})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	if rows := model.(Model).fileRows(); len(rows) != 5 || rows[3].node.name != "bin/" {
+		t.Fatalf("Expected bin/ and share/ under usr/ after expanding, got %d rows", len(rows))
+	}
+	if model.(Model).filesTree != m.filesTree {
+		t.Error("Expected moving and expanding to reuse the file tree")
+	}
+
+	// Filtering shows every match with its directories open.
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "faq" {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	names = nil
+	for _, row := range m.fileRows() {
+		names = append(names, row.node.name)
+	}
+	if !slices.Equal(names, []string{"/", "usr/", "share/", "doc/", "bash/", "FAQ"}) {
+		t.Errorf("Expected only the path to FAQ, got %v", names)
+	}
+	if m.filesSearching || !m.showingFiles {
+		t.Error("Expected Enter to leave the filter applied with the file list open")
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m = model.(Model); m.filesInput.Value() != "" || m.showingFiles {
+		t.Error("Expected Esc to clear the filter, then close the file list")
+	}
+	if m.filesTree == nil || len(m.filesTree.children) != 2 {
+		t.Error("Expected clearing the filter to rebuild the whole tree")
+	}
+}
+
+func TestQueryFilters(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "vim", Repository: "extra", InstalledBytes: 4 << 20},
+		manager.Package{Name: "gvim", Repository: "extra", InstalledBytes: 9 << 20},
+		manager.Package{Name: "vim-plug", IsAUR: true, Votes: 40},
+		manager.Package{Name: "bash", Repository: "core", IsInstalled: true, InstalledBytes: 9 << 20},
+		manager.Package{Name: "glibc", Repository: "core", IsInstalled: true, InstalledBytes: 50 << 20},
+	)
+	names := func() []string {
+		var out []string
+		for _, it := range m.list.Items() {
+			out = append(out, it.(Item).Pkg.Name)
+		}
+		return out
+	}
+
+	m = typeQuery(t, m, "vim repo:extra size:<5M")
+	if got := names(); !slices.Equal(got, []string{"vim"}) {
+		t.Fatalf("Expected only vim, got %v", got)
+	}
+	if it := m.list.Items()[0].(Item); it.Query != "vim" {
+		t.Errorf("Expected the name to be highlighted with the search term, got %q", it.Query)
+	}
+
+	// A broken filter keeps the results and says what is wrong.
+	m.currentQuery = "vim votes:>lots"
+	if m.runSearch() != nil {
+		t.Error("Expected no search for an invalid query")
+	}
+	if !strings.Contains(m.queryErr, "votes: expected a number") || len(m.list.Items()) != 1 {
+		t.Errorf("Expected a parse error over the old results, got %q and %v", m.queryErr, names())
+	}
+
+	// Filters alone go over the whole sync databases.
+	m.currentQuery = "repo:core size:>10M"
+	cmd := m.runSearch()
+	if cmd == nil {
+		t.Fatal("Expected filters alone to search")
+	}
+	m = runCmd(m, cmd).(Model)
+	if got := names(); m.queryErr != "" || !slices.Equal(got, []string{"glibc"}) {
+		t.Errorf("Expected only glibc, got %v (%q)", got, m.queryErr)
+	}
+
+	// Local tabs filter in place, so filters work on their own there.
+	m = m.handleInventory(m.loadInventory()().(inventoryMsg))
+	m.currentQuery = "size:>10M"
+	m.setTab(slices.Index(m.tabs, "INSTALLED"))
+	if got := names(); m.queryErr != "" || !slices.Equal(got, []string{"glibc"}) {
+		t.Errorf("Expected only glibc, got %v (%q)", got, m.queryErr)
+	}
+}
+
+func TestDescriptionSearchToggle(t *testing.T) {
+	m, _ := newTestModel(t,
+		manager.Package{Name: "vim", Repository: "extra", IsInstalled: true, Description: "Vi Improved, a highly configurable text editor"},
+		manager.Package{Name: "editorconfig-core-c", Repository: "extra", Description: "EditorConfig core code"},
+	)
+	m = typeQuery(t, m, "editor")
+	if len(m.list.Items()) != 1 {
+		t.Fatalf("Expected only the name match, got %d items", len(m.list.Items()))
+	}
+
+	model, cmd := tea.Model(m).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	if cmd == nil {
+		t.Fatal("Expected D to search again")
+	}
+	model = runCmd(model, cmd)
+	m = model.(Model)
+	items := m.list.Items()
+	if !m.searchDesc || len(items) != 2 || items[1].(Item).Pkg.Name != "vim" {
+		t.Fatalf("Expected vim listed after the name match, got %v", items)
+	}
+
+	// Only the matched word gets the highlight style.
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+	vim := items[1].(Item)
+	vim.Pkg.Detailed = true
+	valStyle := lipgloss.NewStyle().Foreground(CurrentTheme.Text)
+	highlight := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Background(CurrentTheme.Highlight).Bold(true)
+	want := valStyle.Render("Vi Improved, a highly configurable text ") + highlight.Render("editor")
+	if got := highlightWords(vim.Pkg.Description, vim.Query, valStyle); got != want {
+		t.Errorf("Expected only %q highlighted, got %q", "editor", got)
+	}
+	if view := renderDescription(vim.Pkg, vim.Query, 120); !strings.Contains(view, highlight.Render("editor")) {
+		t.Errorf("Expected the highlighted word in the details, got %q", view)
+	}
+
+	// The INSTALLED tab filters descriptions too, and D refilters it in place.
+	m = m.handleInventory(m.loadInventory()().(inventoryMsg))
+	m.setTab(slices.Index(m.tabs, "INSTALLED"))
+	if len(m.list.Items()) != 1 {
+		t.Fatalf("Expected vim matched by its description, got %d items", len(m.list.Items()))
+	}
+	model, cmd = tea.Model(m).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	if m = model.(Model); cmd != nil || m.searchDesc || len(m.list.Items()) != 0 || m.notice != "Searching names only" {
+		t.Errorf("Expected D to drop vim without searching, got %d items and %q", len(m.list.Items()), m.notice)
+	}
+}
+
+func TestSearchStreamsPerSource(t *testing.T) {
+	m, fake := newTestModel(t,
+		manager.Package{Name: "neovim", Repository: "extra"},
+		manager.Package{Name: "neovim-git", IsAUR: true, Votes: 5},
+	)
+	m.currentQuery = "neovim"
+	batch, ok := m.runSearch()().(tea.BatchMsg)
+	if !ok || len(batch) != len(manager.SearchSources) {
+		t.Fatalf("Expected one search per source, got %v", batch)
+	}
+
+	// The repositories answer first and are listed right away.
+	var model tea.Model = m
+	model, _ = model.Update(batch[0]())
+	m = model.(Model)
+	if len(m.list.Items()) != 1 || !m.isSearching || !m.sourceLoading[manager.SourceAUR] {
+		t.Fatalf("Expected neovim while the AUR is still searching, got %d items", len(m.list.Items()))
+	}
+	if !strings.Contains(m.sourceBadges(), "AUR") {
+		t.Error("Expected a spinner badge for the AUR")
+	}
+	model, _ = model.Update(batch[1]())
+	m = model.(Model)
+	if len(m.list.Items()) != 2 || m.isSearching {
+		t.Fatalf("Expected both results once the AUR answered, got %d items", len(m.list.Items()))
+	}
+
+	// A failing AUR keeps the repository results and gets an error badge.
+	fake.SourceErrs = map[manager.SearchSource]error{manager.SourceAUR: errors.New("connection refused")}
+	m.currentQuery = "neovim"
+	model = runCmd(m, m.runSearch())
+	m = model.(Model)
+	if len(m.list.Items()) != 1 || m.sourceErrs[manager.SourceAUR] == nil {
+		t.Fatalf("Expected neovim and an AUR error, got %d items and %v", len(m.list.Items()), m.sourceErrs)
+	}
+	if !strings.Contains(m.View(), "AUR: connection refused") {
+		t.Error("Expected the AUR error in the status bar")
+	}
+
+	// Results of an older search are dropped.
+	stale := searchResultsMsg{seq: m.searchSeq - 1, query: "neovim", source: manager.SourceAUR, pkgs: []manager.Package{{Name: "stale", IsAUR: true}}}
+	model, _ = model.Update(stale)
+	if n := len(model.(Model).list.Items()); n != 1 {
+		t.Errorf("Expected stale results to be ignored, got %d items", n)
+	}
+}
+
+func TestErrorToasts(t *testing.T) {
+	m, fake := newTestModel(t, manager.Package{Name: "vim", Repository: "extra"})
+	fake.DetailsErr = fmt.Errorf("%w: dial tcp: i/o timeout", manager.ErrOffline)
+	m = typeQuery(t, m, "vim")
+
+	// Failing details end "Loading details..." and can be retried.
+	item := m.list.SelectedItem().(Item)
+	var model tea.Model = m
+	model, _ = model.Update(fetchDetails(item.Pkg)())
+	m = model.(Model)
+	if m.detailsErrs[item.Pkg.QualifiedName()] == nil || m.loadingDetailsFor != "" {
+		t.Fatalf("Expected the details error to be kept, got %v", m.detailsErrs)
+	}
+	if view := m.View(); !strings.Contains(view, "Offline") || strings.Contains(view, "Loading details") {
+		t.Error("Expected the panel and status bar to say the network is offline")
+	}
+	fake.DetailsErr = nil
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	model, _ = model.Update(cmd())
+	m = model.(Model)
+	if !m.list.SelectedItem().(Item).Pkg.Detailed || len(m.toasts) != 0 {
+		t.Fatalf("Expected R to load the details and dismiss the toast, got %d toasts", len(m.toasts))
+	}
+
+	// A locked database stops the transaction until R retries it.
+	fake.DBLocked = true
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil || !strings.Contains(model.(Model).View(), "Database locked") {
+		t.Fatal("Expected the transaction to wait for the database lock")
+	}
+	fake.DBLocked = false
+	model, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	if cmd == nil {
+		t.Error("Expected R to start the transaction once the lock is gone")
+	}
+
+	// Toasts expire; the help screen keeps them.
+	m = model.(Model)
+	model, _ = model.Update(TickMsg(time.Now().Add(time.Minute)))
+	m = model.(Model)
+	if len(m.toasts) != 0 || len(m.errorLog) != 2 {
+		t.Fatalf("Expected toasts to expire into the log, got %d toasts and %d logged", len(m.toasts), len(m.errorLog))
+	}
+	if help := m.helpView(); !strings.Contains(help, "Recent errors") || !strings.Contains(help, "pacman database is locked") {
+		t.Error("Expected the help screen to list recent errors")
+	}
+}
+
+func TestSearchTooBroadSuggestsRefinement(t *testing.T) {
+	m, fake := newTestModel(t, manager.Package{Name: "linux", Repository: "core"})
+	fake.SourceErrs = map[manager.SearchSource]error{
+		manager.SourceAUR: fmt.Errorf("%w for %q", manager.ErrTooManyResults, "li"),
+	}
+	m.searchDesc = true
+	m = typeQuery(t, m, "li")
+	if len(m.list.Items()) != 1 {
+		t.Fatalf("Expected the repository results to stay listed, got %d items", len(m.list.Items()))
+	}
+	if len(m.toasts) != 1 || m.toasts[0].retry != nil {
+		t.Fatalf("Expected one toast without a retry, got %+v", m.toasts)
+	}
+	if msg := m.toasts[0].message; !strings.Contains(msg, "press D to search names only") || !strings.Contains(msg, `longer term than "li"`) {
+		t.Errorf("Expected a refinement suggestion, got %q", msg)
+	}
+
+	// No suggestion for a query the user has already typed past.
+	model := runCmd(m, performSearch(context.Background(), m.searchSeq, "lin", true))
+	if n := len(model.(Model).errorLog); n != 1 {
+		t.Errorf("Expected a replaced query not to be logged, got %d entries", n)
+	}
+
+	// Narrowing the search dismisses the suggestion.
+	fake.SourceErrs = nil
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	m = runCmd(model, cmd).(Model)
+	if len(m.toasts) != 0 || m.searchDesc {
+		t.Errorf("Expected D to search names only without the toast, got %+v", m.toasts)
+	}
+}
@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type newsMsg struct {
+	items []manager.NewsItem
+	since time.Time
+	err   error
+}
+
+// checkNewsBefore holds on to the upgrade command and fetches the Arch news
+// first; the upgrade runs once the news has been read (or there is none).
+func (m *Model) checkNewsBefore(c *exec.Cmd) tea.Cmd {
+	m.pendingUpgrade = c
+	m.checkingNews = true
+	return func() tea.Msg {
+		items, since, err := manager.UnreadNews(context.Background())
+		return newsMsg{items: items, since: since, err: err}
+	}
+}
+
+func (m Model) handleNews(msg newsMsg) (Model, tea.Cmd) {
+	m.checkingNews = false
+	if msg.err != nil {
+		// Never block an upgrade on the feed being unreachable, but say
+		// so before going ahead.
+		m.pushError("news", "Checking Arch news", msg.err, "", nil)
+		m.news, m.newsErr = nil, msg.err
+		m.showingNews = true
+		return m, nil
+	}
+	if len(msg.items) == 0 {
+		return m, m.runPendingUpgrade()
+	}
+	m.news, m.newsSince, m.newsErr = msg.items, msg.since, nil
+	m.showingNews = true
+	return m, nil
+}
+
+func (m *Model) runPendingUpgrade() tea.Cmd {
+	c := m.pendingUpgrade
+	m.pendingUpgrade = nil
+	m.showingNews = false
+	if c == nil {
+		return nil
+	}
+	return m.execTransaction(c, func(err error) tea.Msg { return refreshInstalledStatus() })
+}
+
+func (m Model) needsAcknowledgement() bool {
+	for _, it := range m.news {
+		if it.ManualIntervention {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Model) updateNews(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y":
+		return m, m.runPendingUpgrade()
+	case "enter":
+		if !m.needsAcknowledgement() {
+			return m, m.runPendingUpgrade()
+		}
+	case "esc", "n", "q":
+		m.pendingUpgrade = nil
+		m.showingNews = false
+	}
+	return m, nil
+}
+
+func (m Model) newsView() string {
+	title := HeaderStyle.Render(" ARCH NEWS SINCE LAST UPGRADE ")
+	width := min(m.width-12, 100)
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+
+	var sb strings.Builder
+	sb.WriteByte('\n')
+	switch {
+	case m.newsErr != nil:
+		sb.WriteString(HeaderStyle.Render(" ARCH NEWS UNAVAILABLE "))
+		sb.WriteString("\n\n")
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Width(width).Render("The news feed couldn't be checked: " + m.newsErr.Error()))
+		sb.WriteString("\n")
+		sb.WriteString(gray.Render("Read ") + LinkStyle.Render("https://archlinux.org/news/") + gray.Render(" before upgrading."))
+		sb.WriteString("\n\n")
+	case m.newsSince.IsZero():
+		sb.WriteString(HeaderStyle.Render(" RECENT ARCH NEWS "))
+		sb.WriteString("\n\n")
+		sb.WriteString(gray.Render("The last upgrade isn't in pacman.log, so these are the latest items."))
+		sb.WriteString("\n\n")
+	default:
+		sb.WriteString(title)
+		sb.WriteString("\n\n")
+	}
+
+	for _, it := range m.news {
+		titleStyle := lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true)
+		prefix := "• "
+		if it.ManualIntervention {
+			titleStyle = lipgloss.NewStyle().Foreground(CurrentTheme.Base).Background(CurrentTheme.Red).Bold(true)
+			prefix = "⚠ "
+		}
+		date := gray.Render(it.Published.Format("2006-01-02"))
+		fmt.Fprintf(&sb, "%s %s\n", date, titleStyle.Render(prefix+it.Title))
+
+		desc := it.Description
+		if r := []rune(desc); len(r) > 300 {
+			desc = string(r[:300]) + "…"
+		}
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Text).Width(width).Render(desc))
+		sb.WriteByte('\n')
+		sb.WriteString(LinkStyle.Render(it.Link))
+		sb.WriteString("\n\n")
+	}
+
+	hint := "Enter/y: Continue with upgrade • Esc: Cancel"
+	switch {
+	case m.newsErr != nil:
+		hint = "Enter/y: Upgrade anyway • Esc: Cancel"
+	case m.needsAcknowledgement():
+		hint = "Manual intervention required! Press 'y' to acknowledge and upgrade • Esc: Cancel"
+	}
+	sb.WriteString(gray.Render(hint))
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
+		lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(CurrentTheme.Focus).
+			Padding(1, 4).
+			Render(sb.String()))
+}
@@ -0,0 +1,90 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"gopac/internal/manager"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type orphansMsg struct {
+	optional bool
+	pkgs     []manager.Package
+	err      error
+}
+
+// loadOrphans lists orphans, including the optionally required ones when
+// orphansOptional is set.
+func (m *Model) loadOrphans() tea.Cmd {
+	m.loadingOrphans = true
+	optional := m.orphansOptional
+	return func() tea.Msg {
+		pkgs, err := manager.Orphans(optional)
+		return orphansMsg{optional: optional, pkgs: pkgs, err: err}
+	}
+}
+
+func (m Model) handleOrphans(msg orphansMsg) Model {
+	if msg.optional != m.orphansOptional {
+		// The -Qdtt toggle changed while loading.
+		return m
+	}
+	m.loadingOrphans = false
+	if msg.err == nil {
+		m.orphansLoaded = true
+		m.orphanItems = make([]Item, len(msg.pkgs))
+		for i, pkg := range msg.pkgs {
+			m.orphanItems[i] = Item{Pkg: pkg, ShowSize: true}
+		}
+	}
+	m.updateListItems()
+	return m
+}
+
+// orphanListItems filters the orphans with the search input.
+func (m *Model) orphanListItems(q manager.PackageQuery) []list.Item {
+	query := strings.ToLower(q.Text)
+	var items []list.Item
+	for i := range m.orphanItems {
+		it := &m.orphanItems[i]
+		_, it.MarkedRem = m.markedRemove[it.Pkg.Name]
+		it.Query = q.Text
+		if q.Matches(it.Pkg) && m.localMatches(it.Pkg, query) {
+			items = append(items, *it)
+		}
+	}
+	return items
+}
+
+// queueOrphans marks every listed orphan for removal.
+func (m *Model) queueOrphans() {
+	for _, it := range m.list.Items() {
+		if i, ok := it.(Item); ok {
+			m.markedRemove[i.Pkg.Name] = i.Pkg
+		}
+	}
+	m.updateListItems()
+}
+
+// orphanSummary is the status line for the ORPHANS tab: how many are listed
+// and how much space removing them would free.
+func (m Model) orphanSummary() string {
+	var total int64
+	for _, it := range m.list.Items() {
+		if i, ok := it.(Item); ok {
+			total += i.Pkg.InstalledBytes
+		}
+	}
+	n := len(m.list.Items())
+	kind := "orphan"
+	if n != 1 {
+		kind += "s"
+	}
+	if m.orphansOptional {
+		kind += " + optional"
+	}
+	return fmt.Sprintf("%d %s, %s reclaimable", n, kind, manager.FormatSize(total))
+}
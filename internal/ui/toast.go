@@ -0,0 +1,201 @@
+package ui
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// Toasts leave the status bar after toastLife, or retryToastLife when
+	// they offer a retry.
+	toastLife      = 6 * time.Second
+	retryToastLife = 20 * time.Second
+	maxErrorLog    = 50
+)
+
+// toast is an error shown in the status bar until it expires. Every toast is
+// also kept in the error log on the help screen.
+type toast struct {
+	key     string // a new toast with the same key replaces the old one
+	title   string
+	message string
+	at      time.Time
+	expires time.Time
+	retry   func(m *Model) tea.Cmd // run by R; nil when there's nothing to retry
+}
+
+type detailsErrMsg struct {
+	pkg manager.Package
+	err error
+}
+
+// errorKind names the errors the manager tells apart and what to do about
+// them.
+func errorKind(err error) (title, hint string) {
+	switch {
+	case errors.Is(err, manager.ErrOffline):
+		return "Offline", "check your network connection"
+	case errors.Is(err, manager.ErrRateLimited):
+		return "AUR rate limited", "wait a little before retrying"
+	case errors.Is(err, manager.ErrTooManyResults):
+		return "Too many results", "make the query more specific"
+	case errors.Is(err, manager.ErrQueryTooShort):
+		return "Query too short", "type at least two characters"
+	case errors.Is(err, manager.ErrDBLocked):
+		return "Database locked", "wait for the other pacman to finish"
+	}
+	return "Error", ""
+}
+
+// refinement suggests how to change a search the AUR refused as too broad or
+// too short, or "" for other errors.
+func refinement(query string, desc bool, err error) string {
+	if errors.Is(err, manager.ErrQueryTooShort) {
+		return "type at least two characters"
+	}
+	if !errors.Is(err, manager.ErrTooManyResults) {
+		return ""
+	}
+	q, _ := manager.ParsePackageQuery(query)
+	by, term := manager.ParseSearchQuery(q.Text)
+	switch {
+	case by == manager.ByName && desc:
+		return fmt.Sprintf("press D to search names only, or use a longer term than %q", term)
+	case by == manager.ByNameDesc:
+		return fmt.Sprintf("drop desc: to search names only, or use a longer term than %q", term)
+	}
+	return fmt.Sprintf("use a longer term than %q", term)
+}
+
+// pushError shows that what failed with err, with hint or else the usual
+// advice for its kind. retry, if not nil, is offered with R.
+func (m *Model) pushError(key, what string, err error, hint string, retry func(m *Model) tea.Cmd) {
+	title, kindHint := errorKind(err)
+	hint = cmp.Or(hint, kindHint)
+	message := what + ": " + err.Error()
+	if hint != "" {
+		message += " (" + hint + ")"
+	}
+	now := time.Now()
+	t := toast{key: key, title: title, message: message, at: now, expires: now.Add(toastLife), retry: retry}
+	if retry != nil {
+		t.expires = now.Add(retryToastLife)
+	}
+
+	m.dropToasts(key)
+	m.toasts = append(m.toasts, t)
+	m.errorLog = append(m.errorLog, t)
+	if len(m.errorLog) > maxErrorLog {
+		m.errorLog = m.errorLog[len(m.errorLog)-maxErrorLog:]
+	}
+}
+
+// dropToasts dismisses the toasts whose key starts with prefix, e.g. once
+// what they reported is being retried.
+func (m *Model) dropToasts(prefix string) {
+	m.toasts = slices.DeleteFunc(m.toasts, func(t toast) bool { return strings.HasPrefix(t.key, prefix) })
+}
+
+func (m *Model) expireToasts(now time.Time) {
+	m.toasts = slices.DeleteFunc(m.toasts, func(t toast) bool { return now.After(t.expires) })
+}
+
+// retryToast dismisses the newest toast offering a retry and runs it.
+func (m *Model) retryToast() tea.Cmd {
+	for i := len(m.toasts) - 1; i >= 0; i-- {
+		if t := m.toasts[i]; t.retry != nil {
+			m.toasts = slices.Delete(m.toasts, i, i+1)
+			return t.retry(m)
+		}
+	}
+	return nil
+}
+
+// toastView is the newest toast, for the status bar.
+func (m Model) toastView() string {
+	if len(m.toasts) == 0 {
+		return ""
+	}
+	t := m.toasts[len(m.toasts)-1]
+	text := fmt.Sprintf("   ✗ %s: %s", t.title, t.message)
+	if t.retry != nil {
+		text += " • R: Retry"
+	}
+	if len(m.toasts) > 1 {
+		text += fmt.Sprintf(" (+%d more)", len(m.toasts)-1)
+	}
+	return lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render(text)
+}
+
+// errorLogView lists the recent errors, newest first, for the help screen.
+func (m Model) errorLogView(limit int) string {
+	gray := lipgloss.NewStyle().Foreground(CurrentTheme.Gray)
+	if len(m.errorLog) == 0 {
+		return gray.Render("No errors so far")
+	}
+	var lines []string
+	for i := len(m.errorLog) - 1; i >= 0 && len(lines) < limit; i-- {
+		t := m.errorLog[i]
+		lines = append(lines, gray.Render(t.at.Format("15:04:05"))+" "+
+			lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render(t.title)+" "+t.message)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func fetchDetails(p manager.Package) tea.Cmd {
+	return func() tea.Msg {
+		if err := manager.GetPackageDetails(&p); err != nil {
+			return detailsErrMsg{pkg: p, err: err}
+		}
+		return PackageDetailMsg(p)
+	}
+}
+
+// handleDetailsErr ends "Loading details..." for the package and offers to
+// fetch them again.
+func (m *Model) handleDetailsErr(msg detailsErrMsg) {
+	key := msg.pkg.QualifiedName()
+	if key == m.loadingDetailsFor {
+		m.loadingDetailsFor = ""
+	}
+	m.detailsErrs[key] = msg.err
+	pkg := msg.pkg
+	m.pushError("details:"+key, "Details of "+pkg.Name, msg.err, "", func(m *Model) tea.Cmd {
+		delete(m.detailsErrs, key)
+		m.loadingDetailsFor = key
+		return fetchDetails(pkg)
+	})
+}
+
+func renderDetailsError(p manager.Package, err error, width int) string {
+	header := lipgloss.NewStyle().Foreground(CurrentTheme.RepoOfficial).Bold(true).Render(p.Name)
+	if p.IsAUR {
+		header = lipgloss.NewStyle().Foreground(CurrentTheme.RepoAUR).Bold(true).Render(p.Name)
+	}
+	title, _ := errorKind(err)
+	body := lipgloss.NewStyle().Foreground(CurrentTheme.Red).Render(fmt.Sprintf("Could not load details (%s): %v", title, err))
+	hint := lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("[ R: Retry ]")
+	return lipgloss.NewStyle().Width(width).Render(fmt.Sprintf("\n%s\n\n%s\n\n%s", header, body, hint))
+}
+
+// execTransaction hands the terminal to c, unless another pacman holds the
+// database lock; then it offers to try again instead.
+func (m *Model) execTransaction(c *exec.Cmd, done tea.ExecCallback) tea.Cmd {
+	if err := manager.CheckLock(); err != nil {
+		m.pushError("lock", "Transaction", err, "", func(m *Model) tea.Cmd {
+			return m.execTransaction(c, done)
+		})
+		return nil
+	}
+	return tea.ExecProcess(c, done)
+}
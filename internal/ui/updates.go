@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"gopac/internal/manager"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type updatesMsg struct {
+	pkgs []manager.Package
+	err  error
+}
+
+// loadUpdates starts an update check unless one is already running.
+func (m *Model) loadUpdates() tea.Cmd {
+	if m.checkingUpdates {
+		return nil
+	}
+	m.checkingUpdates = true
+	return func() tea.Msg {
+		pkgs, err := manager.CheckUpdates(context.Background())
+		return updatesMsg{pkgs: pkgs, err: err}
+	}
+}
+
+// updatesBadges marks an incomplete update check: databases that couldn't
+// be refreshed and AUR packages that weren't checked.
+func (m Model) updatesBadges() string {
+	w := m.updatesWarn
+	if w == nil {
+		return ""
+	}
+	var sb strings.Builder
+	if w.Stale != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Yellow).Bold(true).Render("   ⚠ Databases not refreshed, updates may be out of date"))
+	}
+	if w.AUR != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("   ✗ AUR not checked: " + w.AUR.Error()))
+	}
+	return sb.String()
+}
+
+// selectiveUpdateCmd applies the pending updates that aren't held back. With
+// nothing held back it is the usual full upgrade through the AUR helper.
+func (m Model) selectiveUpdateCmd() *exec.Cmd {
+	var (
+		ignore      []string
+		aurPkgs     []string
+		repoPending bool
+		anyHeld     bool
+	)
+	for _, item := range m.updateItems {
+		held := m.heldBack[item.Pkg.Name]
+		anyHeld = anyHeld || held
+		switch {
+		case item.Pkg.IsAUR && !held:
+			aurPkgs = append(aurPkgs, item.Pkg.Name)
+		case !item.Pkg.IsAUR && held:
+			ignore = append(ignore, item.Pkg.Name)
+		case !item.Pkg.IsAUR:
+			repoPending = true
+		}
+	}
+
+	if !anyHeld {
+		return manager.UpdateSystem()
+	}
+	return manager.SelectiveUpdateCmd(ignore, aurPkgs, repoPending)
+}
+
+// nothingToUpgrade explains why U did nothing: every pending update is held
+// back or ignored for the session.
+func (m Model) nothingToUpgrade() string {
+	held := 0
+	for _, item := range m.updateItems {
+		if m.heldBack[item.Pkg.Name] {
+			held++
+		}
+	}
+	return fmt.Sprintf("Nothing to upgrade (%d held)", held)
+}
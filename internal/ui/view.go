@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 
+	"gopac/internal/manager"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -16,12 +18,20 @@ func (m Model) View() string {
 		return m.helpView()
 	}
 
+	if m.showingNews {
+		return m.newsView()
+	}
+
+	if m.showingDowngrade {
+		return m.downgradeView()
+	}
+
 	// Header
 	logo := HeaderStyle.Render(" GOPAC ")
 
 	// Render Tabs
 	var tabViews []string
-	for i, t := range tabs {
+	for i, t := range m.tabs {
 		style := lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Padding(0, 1)
 		if i == m.activeTab {
 			style = lipgloss.NewStyle().
@@ -30,7 +40,7 @@ func (m Model) View() string {
 				Bold(true).
 				Padding(0, 1)
 		}
-		tabViews = append(tabViews, style.Render(t))
+		tabViews = append(tabViews, style.Render(strings.ToUpper(t)))
 	}
 	tabsView := lipgloss.JoinHorizontal(lipgloss.Top, tabViews...)
 
@@ -50,9 +60,10 @@ func (m Model) View() string {
 	availableSearchWidth := max(m.width-fixedContentWidth, 5)
 
 	spin := ""
-	if m.isSearching {
+	if m.checkingUpdates || m.checkingNews || m.loadingHistory || m.loadingOrphans || m.loadingInventory {
 		spin = m.spinner.View() + " "
 	}
+	spin += m.sourceBadges()
 
 	searchView := InputStyle.
 		Width(availableSearchWidth).
@@ -83,13 +94,29 @@ func (m Model) View() string {
 	// Dynamic Status Bar
 	var helpText string
 	if m.searching {
-		helpText = "   SEARCHING • Enter: Confirm • Tab: Focus List • Esc: Cancel " + queueText
+		helpText = "   SEARCHING" + m.searchScope() + " • Enter: Confirm • Tab: Focus List • Esc: Cancel " + queueText
+	} else if m.focusSide == 0 && m.tabs[m.activeTab] == "UPDATES" {
+		helpText = m.updatesBadges() + "   UPDATES • Space: Hold Back/Include • U: Upgrade Selected • ◄/►: Change Filter • ?: Help " + queueText
+	} else if m.focusSide == 0 && m.tabs[m.activeTab] == "INSTALLED" {
+		helpText = "   INSTALLED • " + m.inventorySummary() + " • /: Filter • f: Explicit/Deps/Native/Foreign • s: Sort • Space: Queue • ?: Help " + queueText
+	} else if m.focusSide == 0 && m.tabs[m.activeTab] == "ORPHANS" {
+		helpText = "   ORPHANS • " + m.orphanSummary() + " • Space: Queue • A: Queue All • o: Toggle Optional Deps • I: Apply • ?: Help " + queueText
+	} else if m.focusSide == 0 && m.tabs[m.activeTab] == "HISTORY" {
+		helpText = "   HISTORY • /: Filter (name since:YYYY-MM-DD until:YYYY-MM-DD) • Enter: Package Details • ◄/►: Change Filter • ?: Help " + queueText
 	} else if m.focusSide == 0 {
-		helpText = "   LIST VIEW • ◄/►: Change Filter • Enter: Install • U: Update System • Space: Queue • /: Search • ?: Help " + queueText
+		helpText = "   LIST VIEW" + m.searchScope() + " • ◄/►: Change Filter • Enter: Install • U: Update System • Space: Queue • /: Search • D: Descriptions • ?: Help " + queueText
 	} else {
 		helpText = "   DETAILS • Tab: Focus Search • Esc: Back to List • ?: Help " + queueText
 	}
 
+	if m.notice != "" {
+		helpText = lipgloss.NewStyle().Foreground(CurrentTheme.Green).Bold(true).Render("   ✓ "+m.notice) + helpText
+	}
+	helpText = m.toastView() + helpText
+	if m.queryErr != "" {
+		helpText = lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("   ✗ "+m.queryErr) + helpText
+	}
+
 	statusBar := lipgloss.NewStyle().
 		Width(m.width).
 		Foreground(CurrentTheme.Gray).
@@ -128,7 +155,33 @@ func (m Model) View() string {
 	var listContent string
 	if len(m.list.Items()) == 0 {
 		msg := lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("No Packages Found")
-		if m.isSearching {
+		if m.tabs[m.activeTab] == "UPDATES" {
+			if m.checkingUpdates {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Checking for updates...")
+			} else if m.updatesLoaded {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Green).Bold(true).Render("System is up to date")
+			} else {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("Could not check for updates")
+			}
+		} else if m.tabs[m.activeTab] == "INSTALLED" && m.loadingInventory {
+			msg = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Reading the local database...")
+		} else if m.tabs[m.activeTab] == "ORPHANS" {
+			if m.loadingOrphans {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Looking for orphans...")
+			} else if !m.orphansLoaded {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("Could not read the local database")
+			} else {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Green).Bold(true).Render("No orphans")
+			}
+		} else if m.tabs[m.activeTab] == "HISTORY" {
+			if m.loadingHistory {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Reading pacman.log...")
+			} else if !m.historyLoaded {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("Could not read pacman.log")
+			} else {
+				msg = lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("No history found")
+			}
+		} else if m.isSearching {
 			msg = lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Searching...")
 		}
 		listContent = lipgloss.Place(listViewWidth-4, listViewHeight, lipgloss.Center, lipgloss.Center, msg)
@@ -155,6 +208,34 @@ func (m Model) View() string {
 	)
 }
 
+var sourceLabels = map[manager.SearchSource]string{
+	manager.SourceRepos: "repos",
+	manager.SourceAUR:   "AUR",
+}
+
+// sourceBadges shows a spinner for each source still searching and a red
+// mark for each that failed.
+func (m Model) sourceBadges() string {
+	var sb strings.Builder
+	for _, source := range manager.SearchSources {
+		switch {
+		case m.sourceLoading[source]:
+			sb.WriteString(m.spinner.View() + " " + sourceLabels[source] + " ")
+		case m.sourceErrs[source] != nil:
+			sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Red).Bold(true).Render("✗ "+sourceLabels[source]) + " ")
+		}
+	}
+	return sb.String()
+}
+
+// searchScope marks the status bar while descriptions are searched too.
+func (m Model) searchScope() string {
+	if m.searchDesc {
+		return " (NAMES + DESCRIPTIONS)"
+	}
+	return ""
+}
+
 func (m Model) helpView() string {
 	title := HeaderStyle.Render(" GOPAC HELP ")
 
@@ -163,14 +244,27 @@ func (m Model) helpView() string {
 		Desc string
 	}{
 		{"/", "Search packages"},
+		{"file:<path>", "Search for the packages owning a file"},
+		{"<field>:<term>", "Search by desc, maint, dep, makedep, optdep, checkdep or kw"},
+		{"D", "Search descriptions as well as names"},
+		{"<filter>:<value>", "Filter by repo, installed, aur, outdated, votes, popularity, size, updated or license"},
 		{"U", "Update system packages"},
+		{"Space (UPDATES)", "Hold back/include an update"},
+		{"f/s (INSTALLED)", "Filter by category / change sort order"},
+		{"A/o (ORPHANS)", "Queue all orphans / include optional deps"},
 		{"Tab", "Cycle focus (Search/List/Details)"},
 		{"Space", "Queue/unqueue package"},
 		{"I", "Apply queued changes"},
 		{"C", "Clear queue"},
+		{"R", "Retry what the last error toast reports"},
 		{"Enter", "Install/Remove immediately"},
 		{"h/l or ◄/►", "Change tab filter"},
 		{"p", "View PKGBUILD (AUR only)"},
+		{"d", "Downgrade from the package cache"},
+		{"t", "Dependency tree (Enter opens a node)"},
+		{"r", "Reverse dependencies / why installed"},
+		{"F", "Browse the package's files (/ filters)"},
+		{"x/X", "Export dependency graph as DOT/Mermaid"},
 		{"Up/Down", "Search history (when searching)"},
 		{"Mouse", "Click to focus panels or tabs"},
 		{"?", "Toggle help"},
@@ -189,6 +283,10 @@ func (m Model) helpView() string {
 	}
 
 	sb.WriteByte('\n')
+	sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Focus).Bold(true).Render("Recent errors"))
+	sb.WriteByte('\n')
+	sb.WriteString(m.errorLogView(8))
+	sb.WriteString("\n\n")
 	sb.WriteString(lipgloss.NewStyle().Foreground(CurrentTheme.Gray).Render("Press '?' to close help"))
 
 	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center,
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"gopac/internal/cli"
 	"gopac/internal/config"
 	"gopac/internal/manager"
 	"gopac/internal/ui"
@@ -34,7 +36,9 @@ func main() {
 
 	// Custom Usage
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s graph [flags] [package...]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s provides-cmd [flags] <command>\n\n", os.Args[0])
 		fmt.Fprintln(os.Stderr, "A warm, beautiful TUI for Arch Linux package management.")
 		fmt.Fprintln(os.Stderr, "\nFlags:")
 		flag.VisitAll(func(f *flag.Flag) {
@@ -55,6 +59,8 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  gopac")
 		fmt.Fprintln(os.Stderr, "  gopac -t dracula")
 		fmt.Fprintln(os.Stderr, "  gopac --helper yay")
+		fmt.Fprintln(os.Stderr, "  gopac graph firefox | dot -Tsvg > firefox.svg")
+		fmt.Fprintln(os.Stderr, "  gopac provides-cmd htop")
 	}
 
 	flag.Parse()
@@ -64,6 +70,35 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Subcommands
+	var query string
+	switch flag.Arg(0) {
+	case "graph":
+		if err := cli.Graph(flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "gopac graph: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case "provides-cmd":
+		q, err := cli.ProvidesCmd(flag.Args()[1:], os.Stdout)
+		if errors.Is(err, cli.ErrNoProviders) {
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gopac provides-cmd: %v\n", err)
+			os.Exit(1)
+		}
+		if q == "" {
+			return
+		}
+		query = q
+	case "":
+	default:
+		fmt.Fprintf(os.Stderr, "gopac: unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	// Load config
 	cfg, err := config.Load()
 	if err != nil {
@@ -89,10 +124,29 @@ func main() {
 		manager.SetAURHelper(cfg.AURHelper)
 	}
 
+	if cfg != nil && cfg.NewsURL != "" {
+		manager.SetNewsURL(cfg.NewsURL)
+	}
+
+	if cfg != nil && len(cfg.Ranking) > 0 {
+		weights, err := manager.RankWeightsFrom(cfg.Ranking)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "gopac: config: %v\n", err)
+		}
+		manager.SetScorer(weights)
+	}
+
 	// Pre-warm installed package cache
 	manager.RefreshInstalledCache()
 
-	p := tea.NewProgram(ui.NewModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
+	model := ui.NewModel()
+	if cfg != nil {
+		model = model.WithDescriptionSearch(cfg.SearchDescriptions)
+	}
+	if query != "" {
+		model = model.WithQuery(query)
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)